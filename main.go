@@ -4,36 +4,85 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	sourcepb "github.com/numaproj/numaflow-go/pkg/apis/proto/source/v1"
+	"github.com/numaproj/numaflow-go/pkg/info"
+	"github.com/numaproj/numaflow-go/pkg/shared"
 	"github.com/numaproj/numaflow-go/pkg/sourcer"
+	"go.opentelemetry.io/otel"
 
+	"github.com/numaproj-contrib/kafka-source-go/pkg/cmd"
 	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+	"github.com/numaproj-contrib/kafka-source-go/pkg/health"
 	"github.com/numaproj-contrib/kafka-source-go/pkg/kafka"
+	"github.com/numaproj-contrib/kafka-source-go/pkg/metrics"
+	"github.com/numaproj-contrib/kafka-source-go/pkg/reload"
+	"github.com/numaproj-contrib/kafka-source-go/pkg/tracing"
 	"github.com/numaproj-contrib/kafka-source-go/pkg/utils"
 )
 
+const (
+	// sourceSockAddr and sourceMaxMessageSize are the address and defaultMaxMessageSize constants of the sourcer
+	// package of numaflow-go v0.5.1-0.20230912211616-62600351d97f, as set in go.mod. The sdk doesn't export them,
+	// so the tests check them against its default sourcer options, and an upgrade that changes them fails there.
+	sourceSockAddr       = "/var/run/numaflow/source.sock"
+	sourceMaxMessageSize = 64 * 1024 * 1024
+	// how long the shutdown waits for the clients to close once the inflight acks are drained
+	closeTimeout = 10 * time.Second
+)
+
 func main() {
+	if len(os.Args) > 1 && cmd.IsCommand(os.Args[1]) {
+		os.Exit(cmd.Run(os.Args[1:], os.Stdout, os.Stderr))
+	}
 	logger := utils.NewLogger()
+	// set when the source gave up, once the deferred shutdowns have run.
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
 	// Get the config file path and format from env vars
-	var format string
-	format, ok := os.LookupEnv("CONFIG_FORMAT")
+	format, ok := cmd.ConfigFormat()
 	if !ok {
 		logger.Info("CONFIG_FORMAT not set, defaulting to yaml")
-		format = "yaml"
 	}
 
-	var c *config.Config
-	var err error
-	c, err = getConfigFromEnvVars(format)
+	c, provenance, err := cmd.LoadConfig(format, "")
 	if err != nil {
-		c, err = getConfigFromFile(format)
+		logger.Panic("Failed to load config from ", provenance, " : ", err)
+	}
+	logger.Info("Successfully loaded config from ", provenance)
+	for _, field := range provenance.FieldNames() {
+		logger.Infow("Config value", "field", field, "source", provenance.Source(field))
+	}
+	if err = c.Validate(); err != nil {
+		logger.Panic("Invalid config : ", err)
+	}
+
+	metricsAddr, ok := os.LookupEnv("METRICS_ADDR")
+	if !ok {
+		metricsAddr = metrics.DefaultAddr
+	}
+	metricsServer := metrics.NewServer(metricsAddr)
+	metricsServer.Start()
+	defer func() {
+		_ = metricsServer.Shutdown(context.Background())
+	}()
+
+	if c.Tracing != nil {
+		tp, err := tracing.NewTracerProvider(context.Background(), c.Tracing)
 		if err != nil {
-			logger.Panic("Failed to parse config file : ", err)
-		} else {
-			logger.Info("Successfully parsed config file")
+			logger.Panic("Failed to create tracer provider : ", err)
 		}
-	} else {
-		logger.Info("Successfully parsed config from env vars")
+		otel.SetTracerProvider(tp)
+		defer func() {
+			_ = tp.Shutdown(context.Background())
+		}()
 	}
 
 	logger.Info("Starting Kafka source...")
@@ -41,36 +90,63 @@ func main() {
 	if err != nil {
 		logger.Panic("Failed to create kafka source : ", err)
 	}
-	defer kafkaSrc.Close()
-	err = sourcer.NewServer(kafkaSrc).Start(context.Background())
-	if err != nil {
-		logger.Panic("Failed to start source server : ", err)
-	}
-}
+	health.Register(metricsServer, kafkaSrc)
 
-func getConfigFromFile(format string) (*config.Config, error) {
-	if format == "yaml" {
-		parser := &config.YAMLConfigParser{}
-		content, err := os.ReadFile(fmt.Sprintf("%s/%s", utils.ConfigVolumePath, utils.ConfigFileName))
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	configPath := fmt.Sprintf("%s/%s", utils.ConfigVolumePath, utils.ConfigFileName)
+	if _, err := os.Stat(configPath); err == nil {
+		load := func() (*config.Config, error) {
+			c, _, err := cmd.LoadConfig(format, "")
+			return c, err
+		}
+		watcher, err := reload.NewWatcher(configPath, c, load, utils.NewKafkaVolumeReader(utils.SecretVolumePath), kafkaSrc, logger.Desugar())
 		if err != nil {
-			return nil, err
+			logger.Warn("Not watching the config for changes : ", err)
+		} else {
+			go watcher.Run(ctx)
 		}
-		return parser.Parse(string(content))
-	} else {
-		return nil, fmt.Errorf("invalid config format %s", format)
 	}
+	// the source server is stopped after the shutdown, so that the acks of the messages in flight are still served
+	// while the source drains.
+	serverCtx, stopServer := context.WithCancel(context.Background())
+	defer stopServer()
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- serveSource(serverCtx, kafkaSrc)
+	}()
+	select {
+	case err = <-serverErr:
+		_ = kafkaSrc.Close()
+		logger.Panic("Failed to start source server : ", err)
+	case <-ctx.Done():
+		logger.Info("Received shutdown signal, shutting down Kafka source...")
+	case err = <-kafkaSrc.Err():
+		logger.Error("Kafka source gave up, shutting down : ", err)
+		exitCode = 1
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), kafkaSrc.ShutdownTimeout()+closeTimeout)
+	defer cancel()
+	if err = kafkaSrc.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Failed to shut down kafka source : ", err)
+	}
+	stopServer()
+	if err = <-serverErr; err != nil {
+		logger.Error("Failed to stop source server : ", err)
+	}
+	logger.Info("Kafka source shut down")
 }
 
-func getConfigFromEnvVars(format string) (*config.Config, error) {
-	var c string
-	c, ok := os.LookupEnv("KAFKA_CONFIG")
-	if !ok {
-		return nil, fmt.Errorf("KAFKA_CONFIG environment variable is not set")
-	}
-	if format == "yaml" {
-		parser := &config.YAMLConfigParser{}
-		return parser.Parse(c)
-	} else {
-		return nil, fmt.Errorf("invalid config format %s", format)
+// serveSource serves the source on the socket of the numaflow sdk until ctx is done, then stops gracefully. Unlike
+// the server of the sdk, it doesn't stop on the shutdown signals itself.
+func serveSource(ctx context.Context, src sourcer.Sourcer) error {
+	lis, err := shared.PrepareServer(sourceSockAddr, info.ServerInfoFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s, %w", sourceSockAddr, err)
 	}
+	defer func() { _ = lis.Close() }()
+	grpcServer := shared.CreateGRPCServer(sourceMaxMessageSize)
+	defer grpcServer.GracefulStop()
+	sourcepb.RegisterSourceServer(grpcServer, &sourcer.Service{Source: src})
+	return shared.StartGRPCServer(ctx, grpcServer, lis)
 }
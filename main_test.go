@@ -0,0 +1,18 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/numaproj/numaflow-go/pkg/info"
+	"github.com/numaproj/numaflow-go/pkg/sourcer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeSource_SDKDefaults(t *testing.T) {
+	// the options of the sdk are not exported, they are read with reflection.
+	opts := reflect.ValueOf(sourcer.DefaultOptions()).Elem()
+	assert.Equal(t, sourceSockAddr, opts.FieldByName("sockAddr").String())
+	assert.Equal(t, int64(sourceMaxMessageSize), opts.FieldByName("maxMessageSize").Int())
+	assert.Equal(t, info.ServerInfoFilePath, opts.FieldByName("serverInfoFilePath").String())
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"go.uber.org/zap"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/kafka"
+	"github.com/numaproj-contrib/kafka-source-go/pkg/utils"
+)
+
+const (
+	checkOK      = "ok"
+	checkFailed  = "failed"
+	checkSkipped = "skipped"
+
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// checkResult is the outcome of one of the checks of check-config.
+type checkResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// checkReport is the result of check-config.
+type checkReport struct {
+	Valid  bool          `json:"valid"`
+	Source string        `json:"source,omitempty"`
+	Checks []checkResult `json:"checks"`
+}
+
+func (r *checkReport) add(name string, err error, detail string) bool {
+	result := checkResult{Name: name, Status: checkOK, Detail: detail}
+	if err != nil {
+		result.Status = checkFailed
+		result.Detail = err.Error()
+		r.Valid = false
+	}
+	r.Checks = append(r.Checks, result)
+	return err == nil
+}
+
+func (r *checkReport) skip(name string, detail string) {
+	r.Checks = append(r.Checks, checkResult{Name: name, Status: checkSkipped, Detail: detail})
+}
+
+func runCheckConfig(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("check-config", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var cf configFlags
+	cf.register(flags)
+	connect := flags.Bool("connect", false, "connect to the brokers and check the topic, the consumer group and the ACLs")
+	output := flags.String("output", outputText, "format of the report, text or json")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *output != outputText && *output != outputJSON {
+		_, _ = fmt.Fprintf(stderr, "invalid output %q, must be one of [%s, %s]\n", *output, outputText, outputJSON)
+		return 2
+	}
+
+	r := checkConfig(cf.format, cf.path, cf.reader(), *connect)
+	if err := writeReport(stdout, r, *output); err != nil {
+		_, _ = fmt.Fprintf(stderr, "failed to write the report, %v\n", err)
+		return 2
+	}
+	if !r.Valid {
+		return 1
+	}
+	return 0
+}
+
+// checkConfig loads the config the way the source does, and checks every part of it the source builds at startup.
+func checkConfig(format string, path string, reader utils.VolumeReader, connect bool) *checkReport {
+	r := &checkReport{Valid: true}
+	c, source, err := loadConfig(format, path)
+	r.Source = source
+	if !r.add("load", err, "config loaded from "+source) {
+		return r
+	}
+	r.add("validate", c.Validate(), "")
+	_, err = utils.GetSaramaConfigFromYAMLString(c.Config)
+	r.add("sarama", err, "")
+	if c.TLS == nil {
+		r.skip("tls", "not configured")
+	} else {
+		_, err := utils.GetTLSConfig(c.TLS, reader)
+		r.add("tls", err, "certificates loaded")
+	}
+	if c.SASL == nil {
+		r.skip("sasl", "not configured")
+	} else {
+		_, err := utils.GetSASL(c.SASL, reader)
+		r.add("sasl", err, "credentials loaded")
+	}
+	if !connect {
+		r.skip("connect", "run with -connect to check the brokers")
+		return r
+	}
+	if !r.Valid {
+		r.skip("connect", "the config is invalid")
+		return r
+	}
+	saramaConfig, err := kafka.NewSaramaConfig(c, reader)
+	if err == nil {
+		err = kafka.Preflight(c, saramaConfig, zap.NewNop())
+	}
+	r.add("connect", err, fmt.Sprintf("topic %s can be consumed from %v", c.Topic, c.Brokers))
+	return r
+}
+
+func writeReport(w io.Writer, r *checkReport, output string) error {
+	if output == outputJSON {
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(r)
+	}
+	for _, c := range r.Checks {
+		line := fmt.Sprintf("%-9s %s", "["+c.Status+"]", c.Name)
+		if c.Detail != "" {
+			line += ": " + c.Detail
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	verdict := "config is valid"
+	if !r.Valid {
+		verdict = "config is invalid"
+	}
+	_, err := fmt.Fprintln(w, verdict)
+	return err
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func runJSON(t *testing.T, args ...string) (int, *checkReport) {
+	var stdout, stderr bytes.Buffer
+	code := Run(append([]string{"check-config", "-output", "json"}, args...), &stdout, &stderr)
+	r := &checkReport{}
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), r), stderr.String())
+	return code, r
+}
+
+func statuses(r *checkReport) map[string]string {
+	result := make(map[string]string)
+	for _, c := range r.Checks {
+		result[c.Name] = c.Status
+	}
+	return result
+}
+
+func TestCheckConfig_Valid(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "secrets/kafka-user/user", "admin\n")
+	writeFile(t, dir, "secrets/kafka-user/password", "secret\n")
+	path := writeFile(t, dir, "config.yaml", `
+brokers:
+  - kafka-broker:9092
+topic: test-topic
+consumergroupname: test-group
+sasl:
+  mechanism: PLAIN
+  plain:
+    usersecret:
+      localobjectreference:
+        name: kafka-user
+      key: user
+    passwordsecret:
+      localobjectreference:
+        name: kafka-user
+      key: password
+`)
+	code, r := runJSON(t, "-config-file", path, "-secrets-dir", filepath.Join(dir, "secrets"))
+	assert.Equal(t, 0, code)
+	assert.True(t, r.Valid)
+	assert.Equal(t, path, r.Source)
+	assert.Equal(t, map[string]string{
+		"load":     checkOK,
+		"validate": checkOK,
+		"sarama":   checkOK,
+		"tls":      checkSkipped,
+		"sasl":     checkOK,
+		"connect":  checkSkipped,
+	}, statuses(r))
+}
+
+func TestCheckConfig_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "config.yaml", `
+brokers:
+  - kafka-broker:9092
+tls:
+  certsecret:
+    localobjectreference:
+      name: kafka-tls
+    key: cert
+`)
+	code, r := runJSON(t, "-config-file", path, "-secrets-dir", filepath.Join(dir, "secrets"), "-connect")
+	assert.Equal(t, 1, code)
+	assert.False(t, r.Valid)
+	assert.Equal(t, map[string]string{
+		"load":     checkOK,
+		"validate": checkFailed,
+		"sarama":   checkOK,
+		"tls":      checkFailed,
+		"sasl":     checkSkipped,
+		"connect":  checkSkipped,
+	}, statuses(r))
+	assert.Equal(t, "invalid config:\ntopic: is required\nconsumerGroup: is required\ntls.clientKeySecret: is required with tls.clientCertSecret", r.Checks[1].Detail)
+}
+
+func TestCheckConfig_JSONFormat(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.json", `{"brokers": ["kafka-broker:9092"], "topic": "test-topic", "consumerGroup": "test-group"}`)
+	code, r := runJSON(t, "-format", "json", "-config-file", path)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, checkOK, statuses(r)["validate"])
+
+	code, r = runJSON(t, "-format", "toml", "-config-file", path)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "invalid config format toml, must be one of [json yaml]", r.Checks[0].Detail)
+}
+
+func TestCheckConfig_TextOutput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"check-config", "-config-file", filepath.Join(t.TempDir(), "missing.yaml")}, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout.String(), "[failed]  load: open ")
+	assert.Contains(t, stdout.String(), "config is invalid\n")
+
+	code = Run([]string{"unknown"}, &stdout, &stderr)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr.String(), `unknown command "unknown"`)
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+)
+
+// command is a subcommand of the binary, it returns the exit code of the process.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) int
+}
+
+var commands = []command{
+	{name: "check-config", summary: "validate the config, resolve its secrets and optionally connect to the brokers", run: runCheckConfig},
+	{name: "offsets", summary: "describe or reset the committed offsets of the consumer group", run: runOffsets},
+	{name: "peek", summary: "print the messages the source would emit for the records of a partition", run: runPeek},
+}
+
+// IsCommand reports whether arg names a subcommand, the binary runs the source otherwise.
+func IsCommand(arg string) bool {
+	if arg == "help" || arg == "-h" || arg == "--help" {
+		return true
+	}
+	for _, c := range commands {
+		if c.name == arg {
+			return true
+		}
+	}
+	return false
+}
+
+// Run runs the subcommand named by the first argument and returns the exit code of the process.
+func Run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(args[1:], stdout, stderr)
+		}
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stdout)
+		return 0
+	}
+	_, _ = fmt.Fprintf(stderr, "unknown command %q\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "Usage: kafka-source [command] [flags]")
+	_, _ = fmt.Fprintln(w, "Without a command, the Kafka source is started.")
+	_, _ = fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		_, _ = fmt.Fprintf(w, "  %-14s %s\n", c.name, c.summary)
+	}
+	_, _ = fmt.Fprintln(w, "Run 'kafka-source <command> -h' for the flags of a command.")
+}
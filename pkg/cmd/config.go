@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+	"github.com/numaproj-contrib/kafka-source-go/pkg/utils"
+)
+
+// ConfigFormat returns the format of the config, set by the CONFIG_FORMAT environment variable and yaml by default.
+func ConfigFormat() (string, bool) {
+	format, ok := os.LookupEnv("CONFIG_FORMAT")
+	if !ok {
+		return config.FormatYAML, false
+	}
+	return format, true
+}
+
+// LoadConfig loads the config the way the source does, in layers overriding the fields set by the previous ones: the
+// mounted config file, or the file at path if it is not empty, then the KAFKA_CONFIG environment variable, then the
+// KAFKA_SOURCE_* environment variables overriding single fields. The mounted config file is optional, at least one layer
+// must be set. It returns where every field of the config was loaded from.
+func LoadConfig(format string, path string) (*config.Config, *config.Provenance, error) {
+	p := config.NewProvenance()
+	parser, err := config.NewParser(format)
+	if err != nil {
+		return nil, p, err
+	}
+	c := &config.Config{}
+	mounted := path == ""
+	if mounted {
+		path = fmt.Sprintf("%s/%s", utils.ConfigVolumePath, utils.ConfigFileName)
+	}
+	content, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		p.Layers = append(p.Layers, path)
+		fileConfig, err := parser.Parse(string(content))
+		if err != nil {
+			return nil, p, err
+		}
+		config.Merge(c, fileConfig, path, p)
+	case !mounted || !errors.Is(err, fs.ErrNotExist):
+		p.Layers = append(p.Layers, path)
+		return nil, p, err
+	}
+	if s, ok := os.LookupEnv("KAFKA_CONFIG"); ok {
+		p.Layers = append(p.Layers, "KAFKA_CONFIG")
+		envConfig, err := parser.Parse(s)
+		if err != nil {
+			return nil, p, err
+		}
+		config.Merge(c, envConfig, "KAFKA_CONFIG", p)
+	}
+	if err := config.ApplyEnv(c, os.LookupEnv, p); err != nil {
+		return nil, p, err
+	}
+	if len(p.Layers) == 0 {
+		return nil, p, fmt.Errorf("no config found, mount %s or set the KAFKA_CONFIG or %s* environment variables", path, config.EnvPrefix)
+	}
+	return c, p, nil
+}
+
+// loadConfig loads the config the way the source does, see LoadConfig. It returns the layers the config was loaded from.
+func loadConfig(format string, path string) (*config.Config, string, error) {
+	c, p, err := LoadConfig(format, path)
+	return c, p.String(), err
+}
+
+// configFlags are the flags the subcommands load the config and its secrets with.
+type configFlags struct {
+	format     string
+	path       string
+	secretsDir string
+}
+
+func (f *configFlags) register(flags *flag.FlagSet) {
+	f.format, _ = ConfigFormat()
+	flags.StringVar(&f.format, "format", f.format, "format of the config, defaults to the CONFIG_FORMAT environment variable or yaml")
+	flags.StringVar(&f.path, "config-file", "", "path of the config file, defaults to the mounted config file, overridden by the KAFKA_CONFIG and KAFKA_SOURCE_* environment variables")
+	flags.StringVar(&f.secretsDir, "secrets-dir", utils.SecretVolumePath, "directory the secrets are mounted in, as <secret name>/<key>")
+}
+
+func (f *configFlags) load() (*config.Config, string, error) {
+	return loadConfig(f.format, f.path)
+}
+
+func (f *configFlags) reader() utils.VolumeReader {
+	return utils.NewKafkaVolumeReader(f.secretsDir)
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+func TestLoadConfig_Layers(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.yaml", `
+brokers:
+  - file-broker:9092
+topic: file-topic
+consumergroupname: file-group
+`)
+	t.Setenv("KAFKA_CONFIG", "topic: env-topic\nconsumergroupname: env-group\n")
+	t.Setenv("KAFKA_SOURCE_CONSUMER_GROUP", "override-group")
+	c, p, err := LoadConfig(config.FormatYAML, path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"file-broker:9092"}, c.Brokers)
+	assert.Equal(t, "env-topic", c.Topic)
+	assert.Equal(t, "override-group", c.ConsumerGroupName)
+	assert.Equal(t, []string{path, "KAFKA_CONFIG", "KAFKA_SOURCE_CONSUMER_GROUP"}, p.Layers)
+	assert.Equal(t, path, p.Source("brokers"))
+	assert.Equal(t, "KAFKA_CONFIG", p.Source("topic"))
+	assert.Equal(t, "KAFKA_SOURCE_CONSUMER_GROUP", p.Source("consumerGroup"))
+	assert.Equal(t, config.SourceDefaults, p.Source("shutdownTimeout"))
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	_, p, err := LoadConfig(config.FormatYAML, "/nonexistent/config.yaml")
+	assert.Error(t, err)
+	assert.Equal(t, "/nonexistent/config.yaml", p.String())
+
+	path := writeFile(t, t.TempDir(), "config.yaml", "topic: file-topic\n")
+	t.Setenv("KAFKA_CONFIG", "invalid config string")
+	_, p, err = LoadConfig(config.FormatYAML, path)
+	assert.ErrorContains(t, err, "failed to parse config string")
+	assert.Equal(t, path+", KAFKA_CONFIG", p.String())
+
+	t.Setenv("KAFKA_CONFIG", "topic: env-topic\n")
+	t.Setenv("KAFKA_SOURCE_TLS_INSECURE_SKIP_VERIFY", "maybe")
+	_, _, err = LoadConfig(config.FormatYAML, path)
+	assert.ErrorContains(t, err, "invalid KAFKA_SOURCE_TLS_INSECURE_SKIP_VERIFY environment variable")
+}
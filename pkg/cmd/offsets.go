@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/kafka"
+)
+
+// resetPlan is the output of offsets reset.
+type resetPlan struct {
+	Topic         string              `json:"topic"`
+	ConsumerGroup string              `json:"consumerGroup"`
+	Strategy      kafka.ResetStrategy `json:"strategy"`
+	DryRun        bool                `json:"dryRun"`
+	Partitions    []kafka.OffsetReset `json:"partitions"`
+}
+
+// offsetsDescription is the output of offsets describe.
+type offsetsDescription struct {
+	Topic         string                   `json:"topic"`
+	ConsumerGroup string                   `json:"consumerGroup"`
+	Partitions    []kafka.PartitionOffsets `json:"partitions"`
+}
+
+func runOffsets(args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "describe":
+			return runOffsetsDescribe(args[1:], stdout, stderr)
+		case "reset":
+			return runOffsetsReset(args[1:], stdout, stderr)
+		}
+	}
+	_, _ = fmt.Fprintln(stderr, "Usage: kafka-source offsets describe|reset [flags]")
+	_, _ = fmt.Fprintln(stderr, "  describe  show the committed offset, the log end offset and the lag of every partition")
+	_, _ = fmt.Fprintln(stderr, "  reset     reset the committed offsets of the consumer group, the source must be stopped")
+	return 2
+}
+
+// offsetsFlags are the flags shared by the offsets subcommands.
+type offsetsFlags struct {
+	configFlags
+	partitions string
+	output     string
+}
+
+func (f *offsetsFlags) register(flags *flag.FlagSet) {
+	f.configFlags.register(flags)
+	flags.StringVar(&f.partitions, "partitions", "", "comma separated partitions, defaults to all the partitions of the topic")
+	flags.StringVar(&f.output, "output", outputText, "format of the output, text or json")
+}
+
+// parse validates the flags and returns the partitions.
+func (f *offsetsFlags) parse() ([]int32, error) {
+	if f.output != outputText && f.output != outputJSON {
+		return nil, fmt.Errorf("invalid output %q, must be one of [%s, %s]", f.output, outputText, outputJSON)
+	}
+	return parsePartitions(f.partitions)
+}
+
+// admin loads the config and connects to the brokers.
+func (f *offsetsFlags) admin() (*kafka.OffsetsAdmin, string, string, error) {
+	c, source, err := f.load()
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to load the config from %s, %w", source, err)
+	}
+	if c.ConsumerGroupName == "" {
+		return nil, "", "", fmt.Errorf("no consumerGroup (consumergroupname) in the config loaded from %s", source)
+	}
+	saramaConfig, err := kafka.NewSaramaConfig(c, f.reader())
+	if err != nil {
+		return nil, "", "", err
+	}
+	a, err := kafka.NewOffsetsAdmin(c, saramaConfig)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return a, c.Topic, c.ConsumerGroupName, nil
+}
+
+func runOffsetsDescribe(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("offsets describe", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var of offsetsFlags
+	of.register(flags)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	partitions, err := of.parse()
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	a, topic, group, err := of.admin()
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer func() {
+		_ = a.Close()
+	}()
+	offsets, err := a.Describe(partitions)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "failed to describe the offsets of consumer group %s, %v\n", group, err)
+		return 1
+	}
+	d := &offsetsDescription{Topic: topic, ConsumerGroup: group, Partitions: offsets}
+	if err := writeDescription(stdout, d, of.output); err != nil {
+		_, _ = fmt.Fprintf(stderr, "failed to write the offsets, %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func runOffsetsReset(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("offsets reset", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var of offsetsFlags
+	of.register(flags)
+	flags.Bool(string(kafka.ResetToEarliest), false, "reset to the oldest offset of the partitions")
+	flags.Bool(string(kafka.ResetToLatest), false, "reset to the end offset of the partitions")
+	timestamp := flags.String(string(kafka.ResetToTimestamp), "", "reset to the first offset produced at or after a RFC3339 timestamp")
+	shiftBy := flags.Int64(string(kafka.ResetShiftBy), 0, "move the offsets by a number of records, backwards if negative")
+	explicit := flags.Int64(string(kafka.ResetToExplicit), 0, "reset to an offset")
+	dryRun := flags.Bool("dry-run", false, "print the plan without committing the offsets")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	partitions, err := of.parse()
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 2
+	}
+	strategy, err := resetStrategy(flags)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 2
+	}
+	var at time.Time
+	if strategy == kafka.ResetToTimestamp {
+		if at, err = time.Parse(time.RFC3339, *timestamp); err != nil {
+			_, _ = fmt.Fprintf(stderr, "invalid timestamp %q, %v\n", *timestamp, err)
+			return 2
+		}
+	}
+	value := *shiftBy
+	if strategy == kafka.ResetToExplicit {
+		value = *explicit
+	}
+
+	a, topic, group, err := of.admin()
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer func() {
+		_ = a.Close()
+	}()
+	plan, err := a.Plan(strategy, at, value, partitions)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "failed to plan the reset of consumer group %s, %v\n", group, err)
+		return 1
+	}
+	if !*dryRun {
+		if err := a.Apply(plan); err != nil {
+			_, _ = fmt.Fprintf(stderr, "failed to reset the offsets of consumer group %s, %v\n", group, err)
+			return 1
+		}
+	}
+	p := &resetPlan{Topic: topic, ConsumerGroup: group, Strategy: strategy, DryRun: *dryRun, Partitions: plan}
+	if err := writePlan(stdout, p, of.output); err != nil {
+		_, _ = fmt.Fprintf(stderr, "failed to write the plan, %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// resetStrategy returns the strategy set on the command line, exactly one must be.
+func resetStrategy(flags *flag.FlagSet) (kafka.ResetStrategy, error) {
+	strategies := []kafka.ResetStrategy{kafka.ResetToEarliest, kafka.ResetToLatest, kafka.ResetToTimestamp, kafka.ResetShiftBy, kafka.ResetToExplicit}
+	var set []kafka.ResetStrategy
+	flags.Visit(func(f *flag.Flag) {
+		for _, s := range strategies {
+			if f.Name == string(s) {
+				set = append(set, s)
+			}
+		}
+	})
+	if len(set) != 1 {
+		names := make([]string, 0, len(strategies))
+		for _, s := range strategies {
+			names = append(names, "-"+string(s))
+		}
+		return "", fmt.Errorf("exactly one of %s must be set", strings.Join(names, ", "))
+	}
+	return set[0], nil
+}
+
+// parsePartitions parses a comma separated list of partitions, an empty list means all the partitions.
+func parsePartitions(s string) ([]int32, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var partitions []int32
+	for _, p := range strings.Split(s, ",") {
+		partition, err := strconv.ParseInt(strings.TrimSpace(p), 10, 32)
+		if err != nil || partition < 0 {
+			return nil, fmt.Errorf("invalid partition %q", p)
+		}
+		partitions = append(partitions, int32(partition))
+	}
+	return partitions, nil
+}
+
+func writeDescription(w io.Writer, d *offsetsDescription, output string) error {
+	if output == outputJSON {
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(d)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "TOPIC\tCONSUMER GROUP\tPARTITION\tCOMMITTED\tLOG START\tLOG END\tLAG\n")
+	for _, p := range d.Partitions {
+		committed := "-"
+		if p.Committed >= 0 {
+			committed = strconv.FormatInt(p.Committed, 10)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%d\t%d\t%d\n", d.Topic, d.ConsumerGroup, p.Partition, committed, p.LogStart, p.LogEnd, p.Lag)
+	}
+	return tw.Flush()
+}
+
+func writePlan(w io.Writer, p *resetPlan, output string) error {
+	if output == outputJSON {
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(p)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "TOPIC\tCONSUMER GROUP\tPARTITION\tCURRENT\tTARGET\n")
+	for _, r := range p.Partitions {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\n", p.Topic, p.ConsumerGroup, r.Partition, r.Current, r.Target)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	verdict := "offsets reset"
+	if p.DryRun {
+		verdict = "dry run, no offsets committed"
+	}
+	_, err := fmt.Fprintln(w, verdict)
+	return err
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/kafka"
+)
+
+func TestParsePartitions(t *testing.T) {
+	partitions, err := parsePartitions("")
+	assert.NoError(t, err)
+	assert.Nil(t, partitions)
+	partitions, err = parsePartitions("0, 2,1")
+	assert.NoError(t, err)
+	assert.Equal(t, []int32{0, 2, 1}, partitions)
+	_, err = parsePartitions("0,-1")
+	assert.ErrorContains(t, err, `invalid partition "-1"`)
+}
+
+func TestOffsetsReset_Usage(t *testing.T) {
+	for _, args := range [][]string{
+		{"offsets"},
+		{"offsets", "reset"},
+		{"offsets", "reset", "-to-earliest", "-to-latest"},
+		{"offsets", "reset", "-to-timestamp", "yesterday"},
+		{"offsets", "describe", "-output", "yaml"},
+	} {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, Run(args, &stdout, &stderr), args)
+	}
+}
+
+func TestOffsetsReset_DryRun(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetController(broker.BrokerID()).
+			SetLeader("test-topic", 0, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("test-topic", 0, sarama.OffsetOldest, 10).
+			SetOffset("test-topic", 0, sarama.OffsetNewest, 100),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "test-group", broker),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset("test-group", "test-topic", 0, 50, "", sarama.ErrNoError),
+	})
+	path := writeFile(t, t.TempDir(), "config.yaml", `
+brokers:
+  - `+broker.Addr()+`
+topic: test-topic
+consumergroupname: test-group
+`)
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"offsets", "reset", "-config-file", path, "-shift-by", "-20", "-dry-run", "-output", "json"}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	p := &resetPlan{}
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), p))
+	assert.Equal(t, &resetPlan{
+		Topic:         "test-topic",
+		ConsumerGroup: "test-group",
+		Strategy:      kafka.ResetShiftBy,
+		DryRun:        true,
+		Partitions:    []kafka.OffsetReset{{Partition: 0, Current: 50, Target: 30}},
+	}, p)
+	for _, r := range broker.History() {
+		_, ok := r.Request.(*sarama.OffsetCommitRequest)
+		assert.False(t, ok, "no offsets are committed in a dry run")
+	}
+}
+
+func TestOffsetsReset_NoConsumerGroup(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.yaml", `
+brokers:
+  - kafka-broker:9092
+topic: test-topic
+`)
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 1, Run([]string{"offsets", "reset", "-config-file", path, "-to-earliest"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "no consumerGroup (consumergroupname) in the config loaded from "+path)
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/kafka"
+)
+
+func runPeek(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("peek", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var cf configFlags
+	cf.register(flags)
+	topic := flags.String("topic", "", "topic to read, defaults to the topic of the config")
+	partition := flags.Int("partition", 0, "partition to read")
+	offset := flags.String("offset", "earliest", "offset to read from, earliest, latest or an offset")
+	count := flags.Int("count", 10, "number of records to read")
+	timeout := flags.Duration("timeout", 10*time.Second, "how long to wait for the records")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	from, err := parseOffset(*offset)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if *count <= 0 {
+		_, _ = fmt.Fprintf(stderr, "invalid count %d, must be positive\n", *count)
+		return 2
+	}
+
+	c, source, err := cf.load()
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "failed to load the config from %s, %v\n", source, err)
+		return 1
+	}
+	if *topic != "" {
+		c.Topic = *topic
+	}
+	saramaConfig, err := kafka.NewSaramaConfig(c, cf.reader())
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 1
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	e := json.NewEncoder(stdout)
+	err = kafka.Peek(ctx, c, saramaConfig, int32(*partition), from, *count, func(m kafka.PeekedMessage) error {
+		return e.Encode(m)
+	})
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "failed to peek partition %d of topic %s, %v\n", *partition, c.Topic, err)
+		return 1
+	}
+	return 0
+}
+
+// parseOffset parses the offset to read from, earliest and latest being the oldest and the newest offsets.
+func parseOffset(s string) (int64, error) {
+	switch s {
+	case "earliest":
+		return sarama.OffsetOldest, nil
+	case "latest":
+		return sarama.OffsetNewest, nil
+	}
+	offset, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid offset %q, must be earliest, latest or an offset", s)
+	}
+	return offset, nil
+}
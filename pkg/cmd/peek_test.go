@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOffset(t *testing.T) {
+	offset, err := parseOffset("earliest")
+	assert.NoError(t, err)
+	assert.Equal(t, sarama.OffsetOldest, offset)
+	offset, err = parseOffset("latest")
+	assert.NoError(t, err)
+	assert.Equal(t, sarama.OffsetNewest, offset)
+	offset, err = parseOffset("42")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), offset)
+	_, err = parseOffset("-3")
+	assert.ErrorContains(t, err, `invalid offset "-3"`)
+}
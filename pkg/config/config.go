@@ -1,6 +1,10 @@
 package config
 
-import corev1 "k8s.io/api/core/v1"
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
 
 /**
  * This entire file is a copy of https://github.com/numaproj/numaflow/blob/main/pkg/apis/numaflow/v1alpha1/kafka_source.go with small modifications
@@ -20,8 +24,244 @@ type Config struct {
 	// SASL.enable=true default for SASL.
 	// +optional
 	SASL *SASL `json:"sasl" protobuf:"bytes,6,opt,name=sasl"`
+	// Snapshot turns the source into a compacted-topic snapshot reader, which emits the latest value of every key instead
+	// of consuming the topic through the consumer group. Nil consumes the topic through the consumer group.
+	// +optional
+	Snapshot *Snapshot `json:"snapshot,omitempty" protobuf:"bytes,7,opt,name=snapshot"`
+	// Dedup drops the records whose identity was already seen on their partition within a window, acking them without
+	// emitting them. Nil emits every record.
+	// +optional
+	Dedup *Dedup `json:"dedup,omitempty" protobuf:"bytes,8,opt,name=dedup"`
+	// Chunking reassembles the large messages that producers split into chunks, emitting one message once all of its
+	// chunks are read. Nil emits the chunks as they are.
+	// +optional
+	Chunking *Chunking `json:"chunking,omitempty" protobuf:"bytes,9,opt,name=chunking"`
+	// CloudEvents decodes the records in CloudEvents binary or structured mode, and emits them in the JSON event format
+	// with the CloudEvent time as event time. Nil emits the record values as they are.
+	// +optional
+	CloudEvents *CloudEvents `json:"cloudEvents,omitempty" protobuf:"bytes,10,opt,name=cloudEvents"`
+	// Split explodes the records that batch several events into one message per event. Nil emits one message per record.
+	// +optional
+	Split *Split `json:"split,omitempty" protobuf:"bytes,11,opt,name=split"`
+	// Aggregate packs the records of a partition into aggregated messages. It can't be used together with Split. Nil
+	// emits one message per record.
+	// +optional
+	Aggregate *Aggregate `json:"aggregate,omitempty" protobuf:"bytes,12,opt,name=aggregate"`
+	// PendingRefreshInterval is how often the pending messages are computed in the background, defaults to 5s.
+	// +optional
+	PendingRefreshInterval time.Duration `json:"pendingRefreshInterval,omitempty" protobuf:"bytes,13,opt,name=pendingRefreshInterval"`
+	// TimeLag turns on the time-based lag reporting, computing how far every partition trails the newest record in time
+	// on every pending messages refresh. Nil reports the lag in messages only.
+	// +optional
+	TimeLag *TimeLag `json:"timeLag,omitempty" protobuf:"bytes,14,opt,name=timeLag"`
+	// Tracing turns on the OpenTelemetry tracing of the consumed records, continuing the trace context carried by their
+	// headers. It requires CloudEvents, which carry the context downstream. Nil starts no span.
+	// +optional
+	Tracing *Tracing `json:"tracing,omitempty" protobuf:"bytes,15,opt,name=tracing"`
+	// Health configures the failure thresholds of the liveness probe.
+	// +optional
+	Health *Health `json:"health,omitempty" protobuf:"bytes,16,opt,name=health"`
+	// ShutdownTimeout is how long the source waits on SIGTERM for the acks of the messages in flight before committing
+	// the acked offsets and leaving the consumer group, defaults to 30s.
+	// +optional
+	ShutdownTimeout time.Duration `json:"shutdownTimeout,omitempty" protobuf:"bytes,17,opt,name=shutdownTimeout"`
+	// Retry configures how the source reconnects when creating the kafka clients or consuming fails.
+	// +optional
+	Retry *Retry `json:"retry,omitempty" protobuf:"bytes,18,opt,name=retry"`
+	// SkipPreflight skips the checks of the brokers, the topic, the consumer group and the ACLs when the source is
+	// created.
+	// +optional
+	SkipPreflight bool `json:"skipPreflight,omitempty" protobuf:"bytes,19,opt,name=skipPreflight"`
+}
+
+// Snapshot configures the compacted-topic snapshot mode. The source reads every partition from the oldest offset up to
+// the end offsets captured at startup, keeps the latest record of every key, drops tombstoned keys and emits the result.
+// The consumer group is not used in this mode, so no offsets are committed and every restart rebuilds the snapshot.
+type Snapshot struct {
+	// Stream keeps reading the topic once the snapshot has been emitted, emitting every later record as an incremental update.
+	// +optional
+	Stream bool `json:"stream,omitempty" protobuf:"bytes,1,opt,name=stream"`
+}
+
+// Dedup configures the deduplication window. A record whose identity was already seen on the same partition within the
+// window is acked without being emitted. Windows are kept per partition and survive rebalances for the partitions that
+// stay assigned to this pod.
+type Dedup struct {
+	// By selects the identity of a record, valid inputs - key, header, valueHash
+	By DedupBy `json:"by" protobuf:"bytes,1,opt,name=by,casttype=DedupBy"`
+	// Header is the name of the header that carries the identity, required when By is header
+	// +optional
+	Header string `json:"header,omitempty" protobuf:"bytes,2,opt,name=header"`
+	// Window is how long an identity is remembered, e.g. 10m. Defaults to 0, which bounds the window by size only.
+	// +optional
+	Window time.Duration `json:"window,omitempty" protobuf:"bytes,3,opt,name=window"`
+	// MaxEntries is the maximum number of identities remembered per partition, defaults to 10000.
+	// +optional
+	MaxEntries int `json:"maxEntries,omitempty" protobuf:"bytes,4,opt,name=maxEntries"`
+}
+
+// DedupBy describes what identifies a record for deduplication
+// +enum
+type DedupBy string
+
+const (
+	// DedupByKey uses the Kafka record key
+	DedupByKey DedupBy = "key"
+	// DedupByHeader uses the value of the configured header, such as an idempotency key
+	DedupByHeader DedupBy = "header"
+	// DedupByValueHash uses a hash of the record value
+	DedupByValueHash DedupBy = "valueHash"
+)
+
+// Chunking configures the reassembly of chunked messages. A chunk carries the id of the message it belongs to, its
+// index starting at 0 and the total number of chunks in headers. Records without the message id header are emitted as is.
+type Chunking struct {
+	// MessageIDHeader is the header that carries the message id, defaults to chunk-message-id
+	// +optional
+	MessageIDHeader string `json:"messageIdHeader,omitempty" protobuf:"bytes,1,opt,name=messageIdHeader"`
+	// IndexHeader is the header that carries the chunk index, defaults to chunk-index
+	// +optional
+	IndexHeader string `json:"indexHeader,omitempty" protobuf:"bytes,2,opt,name=indexHeader"`
+	// TotalHeader is the header that carries the total number of chunks, defaults to chunk-total
+	// +optional
+	TotalHeader string `json:"totalHeader,omitempty" protobuf:"bytes,3,opt,name=totalHeader"`
+	// Timeout is how long to wait for the missing chunks of a message before dropping it, defaults to 1m.
+	// +optional
+	Timeout time.Duration `json:"timeout,omitempty" protobuf:"bytes,4,opt,name=timeout"`
+	// MaxBufferBytes caps the size of the chunks buffered for incomplete messages, defaults to 64Mi.
+	// The oldest incomplete messages are dropped when the cap is exceeded.
+	// +optional
+	MaxBufferBytes int `json:"maxBufferBytes,omitempty" protobuf:"bytes,5,opt,name=maxBufferBytes"`
+}
+
+// CloudEvents configures the CloudEvents mode. Records in binary mode (ce_* headers) and in structured mode
+// (application/cloudevents+json) are both emitted in the JSON event format, with the CloudEvent time as event time.
+// Records that are not CloudEvents are emitted as is.
+type CloudEvents struct {
+	// Keys lists the CloudEvent attributes that are mapped into the message keys, in order, defaults to [type, subject].
+	// Attributes missing from an event are skipped.
+	// +optional
+	Keys []string `json:"keys,omitempty" protobuf:"bytes,1,rep,name=keys"`
+}
+
+// Split configures the splitting mode, in which a record batching several events is emitted as one message per event.
+// The offset of the record is only committed once all of its messages are acked. Records that can't be split are
+// emitted as is.
+type Split struct {
+	// Format of the batched records, valid inputs - jsonArray, ndjson
+	Format SplitFormat `json:"format" protobuf:"bytes,1,opt,name=format,casttype=SplitFormat"`
+}
+
+// SplitFormat describes how the events are batched in a record
+// +enum
+type SplitFormat string
+
+const (
+	// SplitFormatJSONArray represents a JSON array of events
+	SplitFormatJSONArray SplitFormat = "jsonArray"
+	// SplitFormatNDJSON represents newline-delimited JSON events
+	SplitFormatNDJSON SplitFormat = "ndjson"
+)
+
+// Aggregate configures the aggregation mode, in which the records of a partition are packed into one message of up to
+// MaxRecords records or MaxBytes bytes. Partially filled messages are emitted when a read times out. Acking an
+// aggregated message acks all of its records, up to the highest contained offset.
+type Aggregate struct {
+	// Format of the aggregated messages, valid inputs - jsonArray, lengthPrefixed
+	Format AggregateFormat `json:"format" protobuf:"bytes,1,opt,name=format,casttype=AggregateFormat"`
+	// MaxRecords is the maximum number of records in an aggregated message, defaults to 100.
+	// +optional
+	MaxRecords int `json:"maxRecords,omitempty" protobuf:"bytes,2,opt,name=maxRecords"`
+	// MaxBytes is the maximum size of the record values in an aggregated message, defaults to 1Mi.
+	// A record larger than MaxBytes is emitted alone.
+	// +optional
+	MaxBytes int `json:"maxBytes,omitempty" protobuf:"bytes,3,opt,name=maxBytes"`
 }
 
+// AggregateFormat describes how the records are packed in an aggregated message
+// +enum
+type AggregateFormat string
+
+const (
+	// AggregateFormatJSONArray packs the records in a JSON array, the values that are not valid JSON are packed as strings
+	AggregateFormatJSONArray AggregateFormat = "jsonArray"
+	// AggregateFormatLengthPrefixed packs every record as a 4 bytes big-endian length followed by the value
+	AggregateFormatLengthPrefixed AggregateFormat = "lengthPrefixed"
+)
+
+// TimeLag configures the time-based lag reporting. On every pending messages refresh, the time lag of every partition
+// is computed as how far the timestamp of the record at the committed offset trails the one of the newest record.
+type TimeLag struct {
+	// Pending reports the time lag converted to an estimated number of messages as the pending messages, so that
+	// autoscaling is driven by the time lag. The estimation uses the rate at which every partition was produced to
+	// since the previous refresh.
+	// +optional
+	Pending bool `json:"pending,omitempty" protobuf:"bytes,1,opt,name=pending"`
+}
+
+// Health configures the liveness probe. The source is reported as not live when the consumer errors keep repeating,
+// or when the consume loop has stopped for too long.
+type Health struct {
+	// ErrorThreshold is the number of consecutive consumer errors, without any record read in between, after which the
+	// source is not live. Defaults to 5.
+	// +optional
+	ErrorThreshold int `json:"errorThreshold,omitempty" protobuf:"bytes,1,opt,name=errorThreshold"`
+	// StallTimeout is how long the consume loop can be stopped, e.g. between two consumer group sessions, before the
+	// source is not live. Defaults to 1m.
+	// +optional
+	StallTimeout time.Duration `json:"stallTimeout,omitempty" protobuf:"bytes,2,opt,name=stallTimeout"`
+}
+
+// Retry configures the reconnections. The failures to create the kafka clients, to join the consumer group or to consume
+// are retried with a jittered exponential backoff, the clients being rebuilt on every retry. Configuration and
+// authorization errors are fatal and not retried.
+type Retry struct {
+	// MaxRetries is the number of consecutive retries after which the source gives up and exits, defaults to 10.
+	// A negative value retries forever.
+	// +optional
+	MaxRetries int `json:"maxRetries,omitempty" protobuf:"bytes,1,opt,name=maxRetries"`
+	// InitialBackoff is the backoff before the first retry, it doubles on every retry. Defaults to 1s.
+	// +optional
+	InitialBackoff time.Duration `json:"initialBackoff,omitempty" protobuf:"bytes,2,opt,name=initialBackoff"`
+	// MaxBackoff caps the backoff between two retries, defaults to 1m.
+	// +optional
+	MaxBackoff time.Duration `json:"maxBackoff,omitempty" protobuf:"bytes,3,opt,name=maxBackoff"`
+}
+
+// Tracing configures the OpenTelemetry tracing. The W3C trace context is extracted from the record headers and a
+// consume span is started for every record, or for every read in batch mode. The context of the record consume span
+// replaces the one in the record headers, and is carried downstream by the traceparent extension in CloudEvents mode.
+type Tracing struct {
+	// Exporter valid inputs - otlp, stdout. Defaults to otlp.
+	// +optional
+	Exporter TracingExporter `json:"exporter,omitempty" protobuf:"bytes,1,opt,name=exporter,casttype=TracingExporter"`
+	// Endpoint of the OTLP gRPC collector, e.g. localhost:4317. Defaults to the OTEL_EXPORTER_OTLP_ENDPOINT environment
+	// variable, or localhost:4317.
+	// +optional
+	Endpoint string `json:"endpoint,omitempty" protobuf:"bytes,2,opt,name=endpoint"`
+	// Insecure disables TLS for the connection to the OTLP collector.
+	// +optional
+	Insecure bool `json:"insecure,omitempty" protobuf:"bytes,3,opt,name=insecure"`
+	// Batch starts one consume span per read, linked to the trace context of every record, instead of one per record.
+	// The record headers are left as they are in batch mode.
+	// +optional
+	Batch bool `json:"batch,omitempty" protobuf:"bytes,4,opt,name=batch"`
+	// ServiceName is the name of the service the spans are reported for, defaults to kafka-source.
+	// +optional
+	ServiceName string `json:"serviceName,omitempty" protobuf:"bytes,5,opt,name=serviceName"`
+}
+
+// TracingExporter describes where the spans are exported to
+// +enum
+type TracingExporter string
+
+const (
+	// TracingExporterOTLP exports the spans to an OTLP gRPC collector
+	TracingExporterOTLP TracingExporter = "otlp"
+	// TracingExporterStdout writes the spans to stdout, for testing
+	TracingExporterStdout TracingExporter = "stdout"
+)
+
 type TLS struct {
 	// +optional
 	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty" protobuf:"bytes,1,opt,name=insecureSkipVerify"`
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// The JSON configs are decoded strictly: every key must be the json tag of a field. The durations are Go duration
+// strings, such as "10s", or numbers of nanoseconds. The duration strings are rewritten to numbers of nanoseconds on the
+// parsed document before it is decoded.
+
+// jsonField returns the field of a struct a JSON key sets, matched case-insensitively as encoding/json does, with the
+// fields of the embedded structs.
+func jsonField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			if inner, ok := jsonField(f.Type, key); ok {
+				return inner, true
+			}
+			continue
+		}
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if strings.EqualFold(name, key) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// normalizeJSON rewrites the duration strings of a parsed document to numbers of nanoseconds.
+func normalizeJSON(v interface{}, t reflect.Type, path string) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch value := v.(type) {
+	case string:
+		if t != durationType {
+			return v, nil
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration %q of %s, %w", value, path, err)
+		}
+		return json.Number(fmt.Sprint(int64(d))), nil
+	case map[string]interface{}:
+		if t.Kind() != reflect.Struct {
+			return v, nil
+		}
+		for key, inner := range value {
+			f, ok := jsonField(t, key)
+			if !ok {
+				// reported by the decoder.
+				continue
+			}
+			fieldPath := key
+			if path != "" {
+				fieldPath = path + "." + key
+			}
+			normalized, err := normalizeJSON(inner, f.Type, fieldPath)
+			if err != nil {
+				return nil, err
+			}
+			value[key] = normalized
+		}
+	case []interface{}:
+		if t.Kind() != reflect.Slice {
+			return v, nil
+		}
+		for i, inner := range value {
+			normalized, err := normalizeJSON(inner, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			value[i] = normalized
+		}
+	}
+	return v, nil
+}
+
+// decodeJSON strictly decodes a JSON config.
+func decodeJSON(configString string) (*Config, error) {
+	d := json.NewDecoder(strings.NewReader(configString))
+	// keeps the numbers of nanoseconds exact.
+	d.UseNumber()
+	var doc interface{}
+	if err := d.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if _, err := d.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("invalid data after the top-level value")
+	}
+	doc, err := normalizeJSON(doc, reflect.TypeOf(Config{}), "")
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	d = json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	if err := d.Decode(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,294 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// EnvPrefix is the prefix of the environment variables that override single fields of the config.
+const EnvPrefix = "KAFKA_SOURCE_"
+
+// SourceDefaults is the source of the fields that no layer sets.
+const SourceDefaults = "defaults"
+
+// Provenance records where the effective config was loaded from. The config is loaded in layers, each one overriding
+// the fields set by the previous ones: defaults < config file < KAFKA_CONFIG < KAFKA_SOURCE_* environment variables.
+type Provenance struct {
+	// Layers are the layers the config was loaded from, in precedence order.
+	Layers []string
+	// Fields maps the path of every field a layer set, e.g. tls.caCertSecret, to the last layer that set it.
+	Fields map[string]string
+}
+
+func NewProvenance() *Provenance {
+	return &Provenance{Fields: make(map[string]string)}
+}
+
+// Source returns the layer the value of a field comes from.
+func (p *Provenance) Source(field string) string {
+	if layer, ok := p.Fields[field]; ok {
+		return layer
+	}
+	return SourceDefaults
+}
+
+// FieldNames returns the paths of the fields set by a layer, sorted.
+func (p *Provenance) FieldNames() []string {
+	fields := make([]string, 0, len(p.Fields))
+	for field := range p.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func (p *Provenance) String() string {
+	if len(p.Layers) == 0 {
+		return SourceDefaults
+	}
+	return strings.Join(p.Layers, ", ")
+}
+
+var secretKeySelectorType = reflect.TypeOf(&corev1.SecretKeySelector{})
+
+// Merge overrides the fields of c with the ones set in the layer config, and records them in the provenance. A field is
+// set when it is not a zero value, so a layer can't reset a field to its zero value. The optional sections set in the
+// layer are created in c if they are missing, even when they are empty.
+func Merge(c *Config, layer *Config, name string, p *Provenance) {
+	if layer == nil {
+		return
+	}
+	mergeValue("", reflect.ValueOf(c).Elem(), reflect.ValueOf(layer).Elem(), name, p)
+}
+
+func mergeValue(path string, dst reflect.Value, src reflect.Value, name string, p *Provenance) {
+	switch {
+	case src.Kind() == reflect.Struct:
+		for i := 0; i < src.NumField(); i++ {
+			mergeValue(fieldPath(path, src.Type().Field(i)), dst.Field(i), src.Field(i), name, p)
+		}
+	case src.Kind() == reflect.Ptr && src.Type() != secretKeySelectorType && src.Type().Elem().Kind() == reflect.Struct:
+		if src.IsNil() {
+			return
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.New(src.Type().Elem()))
+			p.Fields[path] = name
+		}
+		mergeValue(path, dst.Elem(), src.Elem(), name, p)
+	default:
+		if src.IsZero() {
+			return
+		}
+		dst.Set(src)
+		p.Fields[path] = name
+	}
+}
+
+// Diff returns the paths of the top level fields that differ between two configs, e.g. tls when any of the TLS
+// settings differ.
+func Diff(a, b *Config) []string {
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	var changed []string
+	for i := 0; i < va.NumField(); i++ {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			changed = append(changed, fieldPath("", va.Type().Field(i)))
+		}
+	}
+	return changed
+}
+
+// Secrets returns the secrets the TLS and SASL settings refer to.
+func (c *Config) Secrets() []*corev1.SecretKeySelector {
+	var secrets []*corev1.SecretKeySelector
+	add := func(s ...*corev1.SecretKeySelector) {
+		for _, secret := range s {
+			if secret != nil {
+				secrets = append(secrets, secret)
+			}
+		}
+	}
+	if t := c.TLS; t != nil {
+		add(t.CACertSecret, t.CertSecret, t.KeySecret)
+	}
+	if s := c.SASL; s != nil && s.Plain != nil {
+		add(s.Plain.UserSecret, s.Plain.PasswordSecret)
+	}
+	if s := c.SASL; s != nil && s.GSSAPI != nil {
+		add(s.GSSAPI.UsernameSecret, s.GSSAPI.PasswordSecret, s.GSSAPI.KeytabSecret, s.GSSAPI.KerberosConfigSecret)
+	}
+	return secrets
+}
+
+// fieldPath returns the path of a field, named after its json tag.
+func fieldPath(parent string, f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "" {
+		name = f.Name
+	}
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// envOverride is a field of the config that can be overridden with an environment variable.
+type envOverride struct {
+	// name of the environment variable, without EnvPrefix
+	name  string
+	field string
+	set   func(c *Config, value string) error
+}
+
+var envOverrides = []envOverride{
+	{name: "BROKERS", field: "brokers", set: func(c *Config, v string) error {
+		c.Brokers = nil
+		for _, broker := range strings.Split(v, ",") {
+			if broker = strings.TrimSpace(broker); broker != "" {
+				c.Brokers = append(c.Brokers, broker)
+			}
+		}
+		return nil
+	}},
+	{name: "TOPIC", field: "topic", set: func(c *Config, v string) error {
+		c.Topic = v
+		return nil
+	}},
+	{name: "CONSUMER_GROUP", field: "consumerGroup", set: func(c *Config, v string) error {
+		c.ConsumerGroupName = v
+		return nil
+	}},
+	{name: "TLS_INSECURE_SKIP_VERIFY", field: "tls.insecureSkipVerify", set: func(c *Config, v string) (err error) {
+		ensureTLS(c).InsecureSkipVerify, err = strconv.ParseBool(v)
+		return err
+	}},
+	{name: "TLS_CA_CERT_SECRET", field: "tls.caCertSecret", set: func(c *Config, v string) (err error) {
+		ensureTLS(c).CACertSecret, err = parseSecretKeySelector(v)
+		return err
+	}},
+	{name: "TLS_CLIENT_CERT_SECRET", field: "tls.clientCertSecret", set: func(c *Config, v string) (err error) {
+		ensureTLS(c).CertSecret, err = parseSecretKeySelector(v)
+		return err
+	}},
+	{name: "TLS_CLIENT_KEY_SECRET", field: "tls.clientKeySecret", set: func(c *Config, v string) (err error) {
+		ensureTLS(c).KeySecret, err = parseSecretKeySelector(v)
+		return err
+	}},
+	{name: "SASL_MECHANISM", field: "sasl.mechanism", set: func(c *Config, v string) error {
+		mechanism := SASLType(v)
+		ensureSASL(c).Mechanism = &mechanism
+		return nil
+	}},
+	{name: "SASL_PLAIN_USER_SECRET", field: "sasl.plain.userSecret", set: func(c *Config, v string) (err error) {
+		ensurePlain(c).UserSecret, err = parseSecretKeySelector(v)
+		return err
+	}},
+	{name: "SASL_PLAIN_PASSWORD_SECRET", field: "sasl.plain.passwordSecret", set: func(c *Config, v string) (err error) {
+		ensurePlain(c).PasswordSecret, err = parseSecretKeySelector(v)
+		return err
+	}},
+	{name: "SASL_PLAIN_HANDSHAKE", field: "sasl.plain.handshake", set: func(c *Config, v string) (err error) {
+		ensurePlain(c).Handshake, err = strconv.ParseBool(v)
+		return err
+	}},
+	{name: "SASL_GSSAPI_SERVICE_NAME", field: "sasl.gssapi.serviceName", set: func(c *Config, v string) error {
+		ensureGSSAPI(c).ServiceName = v
+		return nil
+	}},
+	{name: "SASL_GSSAPI_REALM", field: "sasl.gssapi.realm", set: func(c *Config, v string) error {
+		ensureGSSAPI(c).Realm = v
+		return nil
+	}},
+	{name: "SASL_GSSAPI_AUTH_TYPE", field: "sasl.gssapi.authType", set: func(c *Config, v string) error {
+		authType := KRB5AuthType(v)
+		ensureGSSAPI(c).AuthType = &authType
+		return nil
+	}},
+	{name: "SASL_GSSAPI_USERNAME_SECRET", field: "sasl.gssapi.usernameSecret", set: func(c *Config, v string) (err error) {
+		ensureGSSAPI(c).UsernameSecret, err = parseSecretKeySelector(v)
+		return err
+	}},
+	{name: "SASL_GSSAPI_PASSWORD_SECRET", field: "sasl.gssapi.passwordSecret", set: func(c *Config, v string) (err error) {
+		ensureGSSAPI(c).PasswordSecret, err = parseSecretKeySelector(v)
+		return err
+	}},
+	{name: "SASL_GSSAPI_KEYTAB_SECRET", field: "sasl.gssapi.keytabSecret", set: func(c *Config, v string) (err error) {
+		ensureGSSAPI(c).KeytabSecret, err = parseSecretKeySelector(v)
+		return err
+	}},
+	{name: "SASL_GSSAPI_KERBEROS_CONFIG_SECRET", field: "sasl.gssapi.kerberosConfigSecret", set: func(c *Config, v string) (err error) {
+		ensureGSSAPI(c).KerberosConfigSecret, err = parseSecretKeySelector(v)
+		return err
+	}},
+}
+
+// EnvVars returns the names of the environment variables that override single fields of the config.
+func EnvVars() []string {
+	names := make([]string, 0, len(envOverrides))
+	for _, o := range envOverrides {
+		names = append(names, EnvPrefix+o.name)
+	}
+	return names
+}
+
+// ApplyEnv overrides the fields of c with the KAFKA_SOURCE_* environment variables returned by lookup, and records them
+// in the provenance. Empty variables are ignored. The TLS and SASL sections are created if they are missing. The secrets
+// are referred to as <secret name>/<key>.
+func ApplyEnv(c *Config, lookup func(string) (string, bool), p *Provenance) error {
+	for _, o := range envOverrides {
+		name := EnvPrefix + o.name
+		v, ok := lookup(name)
+		if !ok || v == "" {
+			continue
+		}
+		p.Layers = append(p.Layers, name)
+		if err := o.set(c, v); err != nil {
+			return fmt.Errorf("invalid %s environment variable %q, %w", name, v, err)
+		}
+		p.Fields[o.field] = name
+	}
+	return nil
+}
+
+func parseSecretKeySelector(v string) (*corev1.SecretKeySelector, error) {
+	name, key, ok := strings.Cut(v, "/")
+	if !ok || name == "" || key == "" || strings.Contains(key, "/") {
+		return nil, fmt.Errorf("a secret must be referred to as <secret name>/<key>")
+	}
+	return &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: key}, nil
+}
+
+func ensureTLS(c *Config) *TLS {
+	if c.TLS == nil {
+		c.TLS = &TLS{}
+	}
+	return c.TLS
+}
+
+func ensureSASL(c *Config) *SASL {
+	if c.SASL == nil {
+		c.SASL = &SASL{}
+	}
+	return c.SASL
+}
+
+func ensurePlain(c *Config) *SASLPlain {
+	s := ensureSASL(c)
+	if s.Plain == nil {
+		s.Plain = &SASLPlain{}
+	}
+	return s.Plain
+}
+
+func ensureGSSAPI(c *Config) *GSSAPI {
+	s := ensureSASL(c)
+	if s.GSSAPI == nil {
+		s.GSSAPI = &GSSAPI{}
+	}
+	return s.GSSAPI
+}
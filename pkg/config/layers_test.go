@@ -0,0 +1,129 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	c := &Config{}
+	p := NewProvenance()
+	Merge(c, &Config{
+		Brokers:           []string{"file-broker:9092"},
+		Topic:             "file-topic",
+		ConsumerGroupName: "file-group",
+		TLS:               &TLS{CACertSecret: secretKeySelector("kafka-tls", "ca.crt")},
+	}, "file", p)
+	Merge(c, &Config{
+		ConsumerGroupName: "env-group",
+		TLS:               &TLS{InsecureSkipVerify: true},
+		Snapshot:          &Snapshot{},
+	}, "KAFKA_CONFIG", p)
+
+	assert.Equal(t, []string{"file-broker:9092"}, c.Brokers)
+	assert.Equal(t, "file-topic", c.Topic)
+	assert.Equal(t, "env-group", c.ConsumerGroupName)
+	assert.Equal(t, &TLS{InsecureSkipVerify: true, CACertSecret: secretKeySelector("kafka-tls", "ca.crt")}, c.TLS)
+	// an empty section still turns on its mode
+	assert.Equal(t, &Snapshot{}, c.Snapshot)
+	assert.Nil(t, c.SASL)
+
+	assert.Equal(t, map[string]string{
+		"brokers":                "file",
+		"topic":                  "file",
+		"consumerGroup":          "KAFKA_CONFIG",
+		"tls":                    "file",
+		"tls.caCertSecret":       "file",
+		"tls.insecureSkipVerify": "KAFKA_CONFIG",
+		"snapshot":               "KAFKA_CONFIG",
+	}, p.Fields)
+	assert.Equal(t, SourceDefaults, p.Source("sasl.mechanism"))
+}
+
+func TestApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"KAFKA_SOURCE_BROKERS":                     "broker-0:9092, broker-1:9092,",
+		"KAFKA_SOURCE_CONSUMER_GROUP":              "env-group",
+		"KAFKA_SOURCE_TOPIC":                       "",
+		"KAFKA_SOURCE_TLS_INSECURE_SKIP_VERIFY":    "false",
+		"KAFKA_SOURCE_SASL_MECHANISM":              "PLAIN",
+		"KAFKA_SOURCE_SASL_PLAIN_USER_SECRET":      "kafka-user/user",
+		"KAFKA_SOURCE_SASL_PLAIN_PASSWORD_SECRET":  "kafka-user/password",
+		"KAFKA_SOURCE_SASL_GSSAPI_KEYTAB_SECRET":   "kafka-kerberos/keytab",
+		"KAFKA_SOURCE_SASL_GSSAPI_AUTH_TYPE":       "KRB5_KEYTAB_AUTH",
+		"KAFKA_SOURCE_UNKNOWN_FIELD":               "ignored",
+		"KAFKA_SOURCE_SASL_GSSAPI_SERVICE_NAME":    "kafka",
+		"KAFKA_SOURCE_TLS_CLIENT_CERT_SECRET":      "kafka-tls/tls.crt",
+		"KAFKA_SOURCE_SASL_GSSAPI_USERNAME_SECRET": "kafka-kerberos/username",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	c := &Config{Topic: "file-topic", TLS: &TLS{InsecureSkipVerify: true}}
+	p := NewProvenance()
+	assert.NoError(t, ApplyEnv(c, lookup, p))
+
+	assert.Equal(t, []string{"broker-0:9092", "broker-1:9092"}, c.Brokers)
+	assert.Equal(t, "file-topic", c.Topic)
+	assert.Equal(t, "env-group", c.ConsumerGroupName)
+	assert.False(t, c.TLS.InsecureSkipVerify)
+	assert.Equal(t, "kafka-tls", c.TLS.CertSecret.Name)
+	assert.Equal(t, "tls.crt", c.TLS.CertSecret.Key)
+	assert.Equal(t, SASLTypePlaintext, *c.SASL.Mechanism)
+	assert.Equal(t, "password", c.SASL.Plain.PasswordSecret.Key)
+	assert.Equal(t, KRB5KeytabAuth, *c.SASL.GSSAPI.AuthType)
+	assert.Equal(t, "kafka", c.SASL.GSSAPI.ServiceName)
+	assert.Equal(t, "KAFKA_SOURCE_CONSUMER_GROUP", p.Source("consumerGroup"))
+	assert.Equal(t, "KAFKA_SOURCE_SASL_PLAIN_USER_SECRET", p.Source("sasl.plain.userSecret"))
+	assert.Equal(t, SourceDefaults, p.Source("topic"))
+	assert.Len(t, p.Layers, 11)
+}
+
+func TestApplyEnv_Invalid(t *testing.T) {
+	for name, value := range map[string]string{
+		"KAFKA_SOURCE_TLS_INSECURE_SKIP_VERIFY": "maybe",
+		"KAFKA_SOURCE_TLS_CA_CERT_SECRET":       "kafka-tls",
+		"KAFKA_SOURCE_SASL_PLAIN_USER_SECRET":   "kafka-user/",
+	} {
+		lookup := func(n string) (string, bool) {
+			return value, n == name
+		}
+		err := ApplyEnv(&Config{}, lookup, NewProvenance())
+		assert.ErrorContains(t, err, "invalid "+name+" environment variable", name)
+	}
+}
+
+func TestEnvVars(t *testing.T) {
+	names := EnvVars()
+	assert.Contains(t, names, "KAFKA_SOURCE_BROKERS")
+	assert.Contains(t, names, "KAFKA_SOURCE_SASL_GSSAPI_KERBEROS_CONFIG_SECRET")
+	seen := make(map[string]bool)
+	for _, o := range envOverrides {
+		assert.False(t, seen[o.field], o.field)
+		seen[o.field] = true
+	}
+}
+
+func TestDiff(t *testing.T) {
+	assert.Empty(t, Diff(fullConfig(), fullConfig()))
+	c := fullConfig()
+	c.Topic = "other-topic"
+	c.TLS.KeySecret = secretKeySelector("kafka-tls", "other.key")
+	c.ShutdownTimeout = 0
+	assert.Equal(t, []string{"topic", "tls", "shutdownTimeout"}, Diff(fullConfig(), c))
+}
+
+func TestSecrets(t *testing.T) {
+	assert.Empty(t, validConfig().Secrets())
+	var names []string
+	for _, s := range fullConfig().Secrets() {
+		names = append(names, s.Name+"/"+s.Key)
+	}
+	assert.Equal(t, []string{
+		"kafka-tls/ca.crt", "kafka-tls/tls.crt", "kafka-tls/tls.key",
+		"kafka-user/user", "kafka-user/password",
+		"kafka-kerberos/username", "kafka-kerberos/password", "kafka-kerberos/keytab", "kafka-kerberos/krb5.conf",
+	}, names)
+}
@@ -1,24 +1,60 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
-	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
 )
 
+const (
+	// FormatYAML is the format of the YAML configs, with the lowercased field names, their camelCase names or their json
+	// tags as keys
+	FormatYAML = "yaml"
+	// FormatJSON is the format of the JSON configs, with the json tags of the fields as keys and Go duration strings or
+	// numbers of nanoseconds as durations
+	FormatJSON = "json"
+)
+
+// parsers are the parsers of the supported config formats.
+var parsers = map[string]func() Parser{
+	FormatYAML: func() Parser { return &YAMLConfigParser{} },
+	FormatJSON: func() Parser { return &JSONConfigParser{} },
+}
+
+// NewParser returns the parser of a config format.
+func NewParser(format string) (Parser, error) {
+	newParser, ok := parsers[format]
+	if !ok {
+		return nil, fmt.Errorf("invalid config format %s, must be one of %v", format, Formats())
+	}
+	return newParser(), nil
+}
+
+// Formats returns the supported config formats.
+func Formats() []string {
+	formats := make([]string, 0, len(parsers))
+	for f := range parsers {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
 // Parser is an interface that defines methods to parse and un-parse Config objects.
 type Parser interface {
 	Parse(configString string) (*Config, error)
 	UnParse(config *Config) (string, error)
 }
 
-// YAMLConfigParser is a parser for YAML formatted configuration strings
+// YAMLConfigParser is a parser for YAML formatted configuration strings. The unknown keys are rejected.
 type YAMLConfigParser struct{}
 
 func (p *YAMLConfigParser) Parse(configString string) (*Config, error) {
-	c := &Config{}
-	err := yaml.Unmarshal([]byte(configString), c)
+	c, err := decodeYAML(configString)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse config string: %w", err)
 	}
@@ -29,7 +65,34 @@ func (p *YAMLConfigParser) UnParse(config *Config) (string, error) {
 	if config == nil {
 		return "", errors.New("config cannot be nil")
 	}
-	b, err := yaml.Marshal(config)
+	var b strings.Builder
+	e := yamlv3.NewEncoder(&b)
+	e.SetIndent(2)
+	if err := e.Encode(config); err != nil {
+		return "", fmt.Errorf("failed to un-parse config: %w", err)
+	}
+	if err := e.Close(); err != nil {
+		return "", fmt.Errorf("failed to un-parse config: %w", err)
+	}
+	return b.String(), nil
+}
+
+// JSONConfigParser is a parser for JSON formatted configuration strings. The unknown keys are rejected.
+type JSONConfigParser struct{}
+
+func (p *JSONConfigParser) Parse(configString string) (*Config, error) {
+	c, err := decodeJSON(configString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config string: %w", err)
+	}
+	return c, nil
+}
+
+func (p *JSONConfigParser) UnParse(config *Config) (string, error) {
+	if config == nil {
+		return "", errors.New("config cannot be nil")
+	}
+	b, err := json.Marshal(config)
 	if err != nil {
 		return "", fmt.Errorf("failed to un-parse config: %w", err)
 	}
@@ -1,33 +1,141 @@
 package config
 
 import (
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
 )
 
-func TestConfigParser_UnParseThenParse(t *testing.T) {
-	var parsers = []Parser{
-		&YAMLConfigParser{},
+func secretKeySelector(name, key string) *corev1.SecretKeySelector {
+	optional := true
+	return &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: name},
+		Key:                  key,
+		Optional:             &optional,
 	}
-	for _, parser := range parsers {
-		testConfig := &Config{
-			Brokers:           []string{"kafka-broker:9092"},
-			Topic:             "test-topic",
-			ConsumerGroupName: "test-consumer-group",
+}
+
+// fullConfig returns a config with every field set.
+func fullConfig() *Config {
+	plain := SASLTypePlaintext
+	keytab := KRB5KeytabAuth
+	return &Config{
+		Brokers:           []string{"kafka-broker-0:9092", "kafka-broker-1:9092"},
+		Topic:             "test-topic",
+		ConsumerGroupName: "test-consumer-group",
+		TLS: &TLS{
+			InsecureSkipVerify: true,
+			CACertSecret:       secretKeySelector("kafka-tls", "ca.crt"),
+			CertSecret:         secretKeySelector("kafka-tls", "tls.crt"),
+			KeySecret:          secretKeySelector("kafka-tls", "tls.key"),
+		},
+		Config: "consumer:\n  fetch:\n    min: 1\n",
+		SASL: &SASL{
+			Mechanism: &plain,
+			GSSAPI: &GSSAPI{
+				ServiceName:          "kafka",
+				Realm:                "EXAMPLE.COM",
+				UsernameSecret:       secretKeySelector("kafka-kerberos", "username"),
+				AuthType:             &keytab,
+				PasswordSecret:       secretKeySelector("kafka-kerberos", "password"),
+				KeytabSecret:         secretKeySelector("kafka-kerberos", "keytab"),
+				KerberosConfigSecret: secretKeySelector("kafka-kerberos", "krb5.conf"),
+			},
+			Plain: &SASLPlain{
+				UserSecret:     secretKeySelector("kafka-user", "user"),
+				PasswordSecret: secretKeySelector("kafka-user", "password"),
+				Handshake:      true,
+			},
+		},
+		Snapshot: &Snapshot{Stream: true},
+		Dedup: &Dedup{
+			By:         DedupByHeader,
+			Header:     "idempotency-key",
+			Window:     10 * time.Minute,
+			MaxEntries: 5000,
+		},
+		Chunking: &Chunking{
+			MessageIDHeader: "message-id",
+			IndexHeader:     "index",
+			TotalHeader:     "total",
+			Timeout:         time.Minute,
+			MaxBufferBytes:  1 << 20,
+		},
+		CloudEvents:            &CloudEvents{Keys: []string{"type"}},
+		Split:                  &Split{Format: SplitFormatNDJSON},
+		Aggregate:              &Aggregate{Format: AggregateFormatLengthPrefixed, MaxRecords: 10, MaxBytes: 1024},
+		PendingRefreshInterval: 5 * time.Second,
+		TimeLag:                &TimeLag{Pending: true},
+		Tracing: &Tracing{
+			Exporter:    TracingExporterStdout,
+			Endpoint:    "localhost:4317",
+			Insecure:    true,
+			Batch:       true,
+			ServiceName: "kafka-source",
+		},
+		Health:          &Health{ErrorThreshold: 3, StallTimeout: 2 * time.Minute},
+		ShutdownTimeout: 45 * time.Second,
+		Retry:           &Retry{MaxRetries: -1, InitialBackoff: time.Second, MaxBackoff: time.Minute},
+		SkipPreflight:   true,
+	}
+}
+
+// zeroFields returns the paths of the fields of v left to their zero value.
+func zeroFields(path string, v reflect.Value) []string {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return []string{path}
 		}
-		configStr, err := parser.UnParse(testConfig)
-		assert.NoError(t, err)
-		config, err := parser.Parse(configStr)
+		return zeroFields(path, v.Elem())
+	case reflect.Struct:
+		var zero []string
+		for i := 0; i < v.NumField(); i++ {
+			zero = append(zero, zeroFields(path+"."+v.Type().Field(i).Name, v.Field(i))...)
+		}
+		return zero
+	default:
+		if v.IsZero() {
+			return []string{path}
+		}
+		return nil
+	}
+}
+
+func TestFullConfig_SetsEveryField(t *testing.T) {
+	// a new config field must be set in fullConfig, so that it is covered by the round trips
+	assert.Empty(t, zeroFields("Config", reflect.ValueOf(fullConfig())))
+}
+
+func TestConfigParser_UnParseThenParse(t *testing.T) {
+	for _, format := range Formats() {
+		parser, err := NewParser(format)
 		assert.NoError(t, err)
-		assert.Equal(t, testConfig, config)
+		for _, testConfig := range []*Config{
+			{
+				Brokers:           []string{"kafka-broker:9092"},
+				Topic:             "test-topic",
+				ConsumerGroupName: "test-consumer-group",
+			},
+			fullConfig(),
+		} {
+			configStr, err := parser.UnParse(testConfig)
+			assert.NoError(t, err)
+			config, err := parser.Parse(configStr)
+			assert.NoError(t, err, format)
+			assert.Equal(t, testConfig, config, format)
+		}
 	}
 }
 
 func TestConfigParser_ParseErrScenarios(t *testing.T) {
 	var parsers = []Parser{
 		&YAMLConfigParser{},
+		&JSONConfigParser{},
 	}
 	for _, parser := range parsers {
 		_, err := parser.Parse("invalid config string")
@@ -38,3 +146,98 @@ func TestConfigParser_ParseErrScenarios(t *testing.T) {
 		assert.True(t, strings.Contains(err.Error(), "config cannot be nil"))
 	}
 }
+
+func TestJSONConfigParser_Parse(t *testing.T) {
+	c, err := (&JSONConfigParser{}).Parse(`{
+  "brokers": ["kafka-broker:9092"],
+  "topic": "test-topic",
+  "consumerGroup": "test-consumer-group",
+  "sasl": {"mechanism": "PLAIN", "plain": {"userSecret": {"name": "kafka-user", "key": "user"}}},
+  "shutdownTimeout": 45000000000
+}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-consumer-group", c.ConsumerGroupName)
+	assert.Equal(t, SASLTypePlaintext, *c.SASL.Mechanism)
+	assert.Equal(t, "kafka-user", c.SASL.Plain.UserSecret.Name)
+	assert.Equal(t, 45*time.Second, c.ShutdownTimeout)
+}
+
+func TestJSONConfigParser_Durations(t *testing.T) {
+	c, err := (&JSONConfigParser{}).Parse(`{
+  "topic": "test-topic",
+  "shutdownTimeout": "10s",
+  "retry": {"initialBackoff": "500ms", "maxBackoff": 60000000000},
+  "dedup": {"by": "key", "window": "1h30m"}
+}`)
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Second, c.ShutdownTimeout)
+	assert.Equal(t, 500*time.Millisecond, c.Retry.InitialBackoff)
+	assert.Equal(t, time.Minute, c.Retry.MaxBackoff)
+	assert.Equal(t, 90*time.Minute, c.Dedup.Window)
+
+	_, err = (&JSONConfigParser{}).Parse(`{"retry": {"initialBackoff": "soon"}}`)
+	assert.ErrorContains(t, err, `invalid duration "soon" of retry.initialBackoff`)
+}
+
+func TestJSONConfigParser_UnknownKeys(t *testing.T) {
+	_, err := (&JSONConfigParser{}).Parse(`{"topic": "test-topic", "consumerGroup": "test-group", "shutdownTimout": "10s"}`)
+	assert.ErrorContains(t, err, `unknown field "shutdownTimout"`)
+	_, err = (&JSONConfigParser{}).Parse(`{"topic": "test-topic", "tls": {"caCertSecret": {"name": "kafka-tls", "keys": "ca.crt"}}}`)
+	assert.ErrorContains(t, err, `unknown field "keys"`)
+	_, err = (&JSONConfigParser{}).Parse(`{"topic": "test-topic"} {}`)
+	assert.Error(t, err)
+}
+
+// TestParse_DocumentedKeys checks that the keys documented in the README are decoded in both formats.
+func TestParse_DocumentedKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		json string
+		want func(c *Config) interface{}
+		// value the key is set to
+		value interface{}
+	}{
+		{
+			name:  "shutdownTimeout",
+			yaml:  "shutdownTimeout: 45s",
+			json:  `{"shutdownTimeout": "45s"}`,
+			want:  func(c *Config) interface{} { return c.ShutdownTimeout },
+			value: 45 * time.Second,
+		},
+		{
+			name:  "retry",
+			yaml:  "retry: {maxRetries: -1, initialBackoff: 2s, maxBackoff: 1m}",
+			json:  `{"retry": {"maxRetries": -1, "initialBackoff": "2s", "maxBackoff": "1m"}}`,
+			want:  func(c *Config) interface{} { return *c.Retry },
+			value: Retry{MaxRetries: -1, InitialBackoff: 2 * time.Second, MaxBackoff: time.Minute},
+		},
+		{
+			name:  "skipPreflight",
+			yaml:  "skipPreflight: true",
+			json:  `{"skipPreflight": true}`,
+			want:  func(c *Config) interface{} { return c.SkipPreflight },
+			value: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for format, doc := range map[string]string{FormatYAML: tt.yaml, FormatJSON: tt.json} {
+				parser, err := NewParser(format)
+				assert.NoError(t, err)
+				c, err := parser.Parse(doc)
+				assert.NoError(t, err, format)
+				assert.Equal(t, tt.value, tt.want(c), format)
+			}
+		})
+	}
+}
+
+func TestNewParser(t *testing.T) {
+	assert.Equal(t, []string{FormatJSON, FormatYAML}, Formats())
+	parser, err := NewParser(FormatJSON)
+	assert.NoError(t, err)
+	assert.IsType(t, &JSONConfigParser{}, parser)
+	_, err = NewParser("toml")
+	assert.EqualError(t, err, "invalid config format toml, must be one of [json yaml]")
+}
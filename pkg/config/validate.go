@@ -0,0 +1,163 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// validator collects the problems of a config, each one prefixed with the path of its field.
+type validator struct {
+	problems []error
+}
+
+func (v *validator) add(field string, format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) required(field string, set bool) {
+	if !set {
+		v.add(field, "is required")
+	}
+}
+
+func (v *validator) secret(field string, s *corev1.SecretKeySelector) {
+	if s == nil {
+		return
+	}
+	if s.Name == "" {
+		v.add(field, "the secret name is required")
+	}
+	if s.Key == "" {
+		v.add(field, "the secret key is required")
+	}
+}
+
+func (v *validator) nonNegative(field string, d time.Duration) {
+	if d < 0 {
+		v.add(field, "must not be negative, got %s", d)
+	}
+}
+
+// Validate checks the fields of the config the source can't start without, the fields that depend on each other and
+// the enum values. All the problems found are reported in one error, with the paths of their fields.
+func (c *Config) Validate() error {
+	v := &validator{}
+	v.required("brokers", len(c.Brokers) > 0)
+	for i, broker := range c.Brokers {
+		if broker == "" {
+			v.add(fmt.Sprintf("brokers[%d]", i), "must not be empty")
+		}
+	}
+	v.required("topic", c.Topic != "")
+	// the snapshot mode doesn't use the consumer group.
+	v.required("consumerGroup", c.ConsumerGroupName != "" || c.Snapshot != nil)
+	c.TLS.validate(v)
+	c.SASL.validate(v)
+	if c.Dedup != nil {
+		switch c.Dedup.By {
+		case DedupByKey, DedupByValueHash:
+		case DedupByHeader:
+			v.required("dedup.header", c.Dedup.Header != "")
+		default:
+			v.add("dedup.by", "failed to parse dedup by %q. Must be one of the following: ['%s', '%s', '%s']", c.Dedup.By, DedupByKey, DedupByHeader, DedupByValueHash)
+		}
+		v.nonNegative("dedup.window", c.Dedup.Window)
+	}
+	if c.Chunking != nil {
+		v.nonNegative("chunking.timeout", c.Chunking.Timeout)
+	}
+	if c.Split != nil && c.Split.Format != SplitFormatJSONArray && c.Split.Format != SplitFormatNDJSON {
+		v.add("split.format", "failed to parse split format %q. Must be one of the following: ['%s', '%s']", c.Split.Format, SplitFormatJSONArray, SplitFormatNDJSON)
+	}
+	if c.Aggregate != nil {
+		if c.Aggregate.Format != AggregateFormatJSONArray && c.Aggregate.Format != AggregateFormatLengthPrefixed {
+			v.add("aggregate.format", "failed to parse aggregate format %q. Must be one of the following: ['%s', '%s']", c.Aggregate.Format, AggregateFormatJSONArray, AggregateFormatLengthPrefixed)
+		}
+		if c.Split != nil {
+			v.add("aggregate", "can't be combined with split")
+		}
+	}
+	if c.Tracing != nil && c.CloudEvents == nil {
+		// the SDK messages have no headers.
+		v.add("tracing", "requires cloudEvents, the trace context is only carried downstream by the distributed tracing extension of the CloudEvents")
+	}
+	if c.Tracing != nil && c.Tracing.Exporter != "" && c.Tracing.Exporter != TracingExporterOTLP && c.Tracing.Exporter != TracingExporterStdout {
+		v.add("tracing.exporter", "failed to parse tracing exporter %q. Must be one of the following: ['%s', '%s']", c.Tracing.Exporter, TracingExporterOTLP, TracingExporterStdout)
+	}
+	if c.Health != nil {
+		v.nonNegative("health.stallTimeout", c.Health.StallTimeout)
+	}
+	if c.Retry != nil {
+		v.nonNegative("retry.initialBackoff", c.Retry.InitialBackoff)
+		v.nonNegative("retry.maxBackoff", c.Retry.MaxBackoff)
+	}
+	v.nonNegative("pendingRefreshInterval", c.PendingRefreshInterval)
+	v.nonNegative("shutdownTimeout", c.ShutdownTimeout)
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid config:\n%w", errors.Join(v.problems...))
+}
+
+func (t *TLS) validate(v *validator) {
+	if t == nil {
+		return
+	}
+	v.secret("tls.caCertSecret", t.CACertSecret)
+	v.secret("tls.clientCertSecret", t.CertSecret)
+	v.secret("tls.clientKeySecret", t.KeySecret)
+	if t.CertSecret != nil && t.KeySecret == nil {
+		v.add("tls.clientKeySecret", "is required with tls.clientCertSecret")
+	}
+	if t.KeySecret != nil && t.CertSecret == nil {
+		v.add("tls.clientCertSecret", "is required with tls.clientKeySecret")
+	}
+}
+
+func (s *SASL) validate(v *validator) {
+	if s == nil {
+		return
+	}
+	if s.Mechanism == nil {
+		v.add("sasl.mechanism", "is required")
+	} else {
+		// the other mechanisms are not supported by the source.
+		switch *s.Mechanism {
+		case SASLTypePlaintext:
+			v.required("sasl.plain", s.Plain != nil)
+		case SASLTypeGSSAPI:
+			v.required("sasl.gssapi", s.GSSAPI != nil)
+		default:
+			v.add("sasl.mechanism", "failed to parse SASL mechanism %q. Must be one of the following: ['%s', '%s']", *s.Mechanism, SASLTypePlaintext, SASLTypeGSSAPI)
+		}
+	}
+	if p := s.Plain; p != nil {
+		v.required("sasl.plain.userSecret", p.UserSecret != nil)
+		// the password is optional, its reference is only checked when it is set.
+		v.secret("sasl.plain.userSecret", p.UserSecret)
+		v.secret("sasl.plain.passwordSecret", p.PasswordSecret)
+	}
+	if g := s.GSSAPI; g != nil {
+		v.required("sasl.gssapi.serviceName", g.ServiceName != "")
+		v.required("sasl.gssapi.realm", g.Realm != "")
+		v.required("sasl.gssapi.usernameSecret", g.UsernameSecret != nil)
+		v.required("sasl.gssapi.kerberosConfigSecret", g.KerberosConfigSecret != nil)
+		switch {
+		case g.AuthType == nil:
+			v.add("sasl.gssapi.authType", "is required")
+		case *g.AuthType == KRB5UserAuth:
+			v.required("sasl.gssapi.passwordSecret", g.PasswordSecret != nil)
+		case *g.AuthType == KRB5KeytabAuth:
+			v.required("sasl.gssapi.keytabSecret", g.KeytabSecret != nil)
+		default:
+			v.add("sasl.gssapi.authType", "failed to parse GSSAPI AuthType %q. Must be one of the following: ['%s', '%s']", *g.AuthType, KRB5UserAuth, KRB5KeytabAuth)
+		}
+		v.secret("sasl.gssapi.usernameSecret", g.UsernameSecret)
+		v.secret("sasl.gssapi.passwordSecret", g.PasswordSecret)
+		v.secret("sasl.gssapi.keytabSecret", g.KeytabSecret)
+		v.secret("sasl.gssapi.kerberosConfigSecret", g.KerberosConfigSecret)
+	}
+}
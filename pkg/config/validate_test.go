@@ -0,0 +1,113 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validConfig() *Config {
+	return &Config{
+		Brokers:           []string{"kafka-broker:9092"},
+		Topic:             "test-topic",
+		ConsumerGroupName: "test-consumer-group",
+	}
+}
+
+// problems returns the problems reported by Validate, one per line.
+func problems(t *testing.T, c *Config) []string {
+	err := c.Validate()
+	if err == nil {
+		return nil
+	}
+	lines := strings.Split(err.Error(), "\n")
+	assert.Equal(t, "invalid config:", lines[0])
+	return lines[1:]
+}
+
+func TestValidate_Valid(t *testing.T) {
+	assert.NoError(t, validConfig().Validate())
+
+	c := fullConfig()
+	// aggregate can't be combined with split
+	c.Split = nil
+	assert.NoError(t, c.Validate())
+
+	c = validConfig()
+	c.ConsumerGroupName = ""
+	c.Snapshot = &Snapshot{}
+	assert.NoError(t, c.Validate())
+}
+
+func TestValidate_ReportsEveryProblem(t *testing.T) {
+	assert.Equal(t, []string{
+		"brokers: is required",
+		"topic: is required",
+		"consumerGroup: is required",
+	}, problems(t, &Config{}))
+
+	keytab := KRB5KeytabAuth
+	c := validConfig()
+	c.Brokers = append(c.Brokers, "")
+	c.TLS = &TLS{CertSecret: secretKeySelector("kafka-tls", "")}
+	c.SASL = &SASL{
+		Plain:  &SASLPlain{UserSecret: secretKeySelector("kafka-user", "user")},
+		GSSAPI: &GSSAPI{ServiceName: "kafka", Realm: "EXAMPLE.COM", AuthType: &keytab},
+	}
+	c.Dedup = &Dedup{By: DedupByHeader, Window: -time.Second}
+	c.Split = &Split{Format: "csv"}
+	c.Aggregate = &Aggregate{Format: AggregateFormatJSONArray}
+	c.Tracing = &Tracing{Exporter: "zipkin"}
+	assert.Equal(t, []string{
+		"brokers[1]: must not be empty",
+		"tls.clientCertSecret: the secret key is required",
+		"tls.clientKeySecret: is required with tls.clientCertSecret",
+		"sasl.mechanism: is required",
+		"sasl.gssapi.usernameSecret: is required",
+		"sasl.gssapi.kerberosConfigSecret: is required",
+		"sasl.gssapi.keytabSecret: is required",
+		"dedup.header: is required",
+		"dedup.window: must not be negative, got -1s",
+		`split.format: failed to parse split format "csv". Must be one of the following: ['jsonArray', 'ndjson']`,
+		"aggregate: can't be combined with split",
+		"tracing: requires cloudEvents, the trace context is only carried downstream by the distributed tracing extension of the CloudEvents",
+		`tracing.exporter: failed to parse tracing exporter "zipkin". Must be one of the following: ['otlp', 'stdout']`,
+	}, problems(t, c))
+}
+
+func TestValidate_SASLPlainWithoutPassword(t *testing.T) {
+	plain := SASLTypePlaintext
+	c := validConfig()
+	c.SASL = &SASL{Mechanism: &plain, Plain: &SASLPlain{UserSecret: secretKeySelector("kafka-user", "user")}}
+	assert.NoError(t, c.Validate())
+
+	c.SASL.Plain.PasswordSecret = secretKeySelector("kafka-user", "")
+	assert.Equal(t, []string{"sasl.plain.passwordSecret: the secret key is required"}, problems(t, c))
+}
+
+func TestValidate_SASLEnums(t *testing.T) {
+	scram := SASLTypeSCRAMSHA512
+	c := validConfig()
+	c.SASL = &SASL{Mechanism: &scram}
+	assert.Equal(t, []string{
+		`sasl.mechanism: failed to parse SASL mechanism "SCRAM-SHA-512". Must be one of the following: ['PLAIN', 'GSSAPI']`,
+	}, problems(t, c))
+
+	gssapi := SASLTypeGSSAPI
+	ccache := KRB5AuthType("KRB5_CCACHE_AUTH")
+	c.SASL = &SASL{Mechanism: &gssapi}
+	assert.Equal(t, []string{"sasl.gssapi: is required"}, problems(t, c))
+	c.SASL.GSSAPI = &GSSAPI{
+		ServiceName:          "kafka",
+		Realm:                "EXAMPLE.COM",
+		UsernameSecret:       secretKeySelector("kafka-kerberos", "username"),
+		KerberosConfigSecret: secretKeySelector("kafka-kerberos", "krb5.conf"),
+	}
+	assert.Equal(t, []string{"sasl.gssapi.authType: is required"}, problems(t, c))
+	c.SASL.GSSAPI.AuthType = &ccache
+	assert.Equal(t, []string{
+		`sasl.gssapi.authType: failed to parse GSSAPI AuthType "KRB5_CCACHE_AUTH". Must be one of the following: ['KRB5_USER_AUTH', 'KRB5_KEYTAB_AUTH']`,
+	}, problems(t, c))
+}
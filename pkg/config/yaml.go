@@ -0,0 +1,236 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// The YAML configs are decoded strictly: every key must name a field, with one of its aliases. The aliases of a field
+// are its lowercased name (consumergroupname), its camelCase name (consumerGroupName) and its json tag (consumerGroup).
+// The fields of an inlined struct, such as the name of a secret, can be set at the level of the struct that inlines it.
+// The keys are checked and rewritten to the lowercased names on the parsed document, so that the problems are reported
+// with their lines, before it is decoded.
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// yamlField is a field of a struct, as addressed by the keys of a YAML mapping.
+type yamlField struct {
+	// index of the field, and of the field within the inlined struct for the fields of an inlined struct
+	index []int
+	// key of the field once the keys are rewritten
+	key string
+	typ reflect.Type
+}
+
+// yamlFields returns the fields of a struct by alias.
+func yamlFields(t reflect.Type) map[string]yamlField {
+	fields := make(map[string]yamlField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		field := yamlField{index: []int{i}, key: strings.ToLower(f.Name), typ: f.Type}
+		for _, alias := range yamlAliases(f) {
+			fields[alias] = field
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && strings.Contains(f.Tag.Get("json"), "inline") {
+			for j := 0; j < f.Type.NumField(); j++ {
+				inner := f.Type.Field(j)
+				innerField := yamlField{index: []int{i, j}, key: strings.ToLower(inner.Name), typ: inner.Type}
+				for _, alias := range yamlAliases(inner) {
+					if _, ok := fields[alias]; !ok {
+						fields[alias] = innerField
+					}
+				}
+			}
+		}
+	}
+	return fields
+}
+
+func yamlAliases(f reflect.StructField) []string {
+	aliases := []string{strings.ToLower(f.Name), lowerCamel(f.Name)}
+	if name := strings.Split(f.Tag.Get("json"), ",")[0]; name != "" {
+		aliases = append(aliases, name)
+	}
+	return aliases
+}
+
+// lowerCamel lowercases the leading upper case letters of a name, but the one starting the next word:
+// CACertSecret becomes caCertSecret and TLS becomes tls.
+func lowerCamel(name string) string {
+	r := []rune(name)
+	for i := 0; i < len(r) && unicode.IsUpper(r[i]); i++ {
+		if i > 0 && i+1 < len(r) && unicode.IsLower(r[i+1]) {
+			break
+		}
+		r[i] = unicode.ToLower(r[i])
+	}
+	return string(r)
+}
+
+// yamlNormalizer checks the keys of a document against the fields of the config and rewrites them.
+type yamlNormalizer struct {
+	problems []error
+}
+
+func (n *yamlNormalizer) add(node *yamlv3.Node, format string, args ...interface{}) {
+	n.problems = append(n.problems, fmt.Errorf("line %d: %s", node.Line, fmt.Sprintf(format, args...)))
+}
+
+func (n *yamlNormalizer) normalize(node *yamlv3.Node, t reflect.Type, path string) {
+	switch {
+	case node.Kind == yamlv3.DocumentNode:
+		for _, c := range node.Content {
+			n.normalize(c, t, path)
+		}
+	case node.Kind == yamlv3.AliasNode:
+		n.normalize(node.Alias, t, path)
+	case t.Kind() == reflect.Ptr:
+		n.normalize(node, t.Elem(), path)
+	case t.Kind() == reflect.Struct && node.Kind == yamlv3.MappingNode:
+		n.normalizeMapping(node, t, path)
+	case t.Kind() == reflect.Slice && node.Kind == yamlv3.SequenceNode:
+		for i, c := range node.Content {
+			n.normalize(c, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case t == durationType && node.Kind == yamlv3.ScalarNode && node.Tag == "!!int":
+		// durations used to be decoded from numbers of nanoseconds as well.
+		node.Value, node.Tag = node.Value+"ns", "!!str"
+	}
+}
+
+func (n *yamlNormalizer) normalizeMapping(node *yamlv3.Node, t reflect.Type, path string) {
+	fields := yamlFields(t)
+	// keys already set, as written in the document
+	seen := make(map[string]yamlv3.Node)
+	inlined := make(map[int][]*yamlv3.Node)
+	content := make([]*yamlv3.Node, 0, len(node.Content))
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Tag == "!!merge" {
+			n.normalize(value, t, path)
+			content = append(content, key, value)
+			continue
+		}
+		f, ok := fields[key.Value]
+		if !ok {
+			n.add(key, "unknown key %q in %s%s", key.Value, describePath(path), suggest(key.Value, fields))
+			continue
+		}
+		id := fmt.Sprint(f.index)
+		if prev, ok := seen[id]; ok {
+			n.add(key, "key %q sets the same field as %q on line %d", key.Value, prev.Value, prev.Line)
+			continue
+		}
+		seen[id] = *key
+		fieldPath := key.Value
+		if path != "" {
+			fieldPath = path + "." + key.Value
+		}
+		key.Value = f.key
+		n.normalize(value, f.typ, fieldPath)
+		if len(f.index) > 1 {
+			inlined[f.index[0]] = append(inlined[f.index[0]], key, value)
+			continue
+		}
+		content = append(content, key, value)
+	}
+	for i, pairs := range inlined {
+		if prev, ok := seen[fmt.Sprint([]int{i})]; ok {
+			n.add(pairs[0], "key %q can't be set together with %q on line %d", pairs[0].Value, prev.Value, prev.Line)
+			continue
+		}
+		content = append(content,
+			&yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: strings.ToLower(t.Field(i).Name), Line: pairs[0].Line},
+			&yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map", Content: pairs, Line: pairs[0].Line},
+		)
+	}
+	node.Content = content
+}
+
+func describePath(path string) string {
+	if path == "" {
+		return "the config"
+	}
+	return path
+}
+
+// suggest returns a hint naming the alias closest to an unknown key, if it looks like a typo of it.
+func suggest(key string, fields map[string]yamlField) string {
+	best, bestDistance := "", 3
+	aliases := make([]string, 0, len(fields))
+	for alias := range fields {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		if d := editDistance(strings.ToLower(key), strings.ToLower(alias)); d < bestDistance {
+			best, bestDistance = alias, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(", did you mean %q?", best)
+}
+
+// editDistance returns the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// decodeYAML strictly decodes a YAML config.
+func decodeYAML(configString string) (*Config, error) {
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal([]byte(configString), &doc); err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if doc.Kind == 0 {
+		return c, nil
+	}
+	n := &yamlNormalizer{}
+	n.normalize(&doc, reflect.TypeOf(c), "")
+	if len(n.problems) > 0 {
+		return nil, errors.Join(n.problems...)
+	}
+	if err := doc.Decode(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestYAMLConfigParser_Aliases(t *testing.T) {
+	want := &Config{
+		Brokers:           []string{"kafka-broker:9092"},
+		Topic:             "test-topic",
+		ConsumerGroupName: "test-consumer-group",
+		TLS: &TLS{
+			InsecureSkipVerify: true,
+			CertSecret:         secretKeySelector("kafka-tls", "tls.crt"),
+			KeySecret:          secretKeySelector("kafka-tls", "tls.key"),
+		},
+		ShutdownTimeout: 45 * time.Second,
+	}
+	for _, doc := range []string{
+		`
+brokers: [kafka-broker:9092]
+topic: test-topic
+consumergroupname: test-consumer-group
+tls:
+  insecureskipverify: true
+  certsecret:
+    localobjectreference:
+      name: kafka-tls
+    key: tls.crt
+    optional: true
+  keysecret:
+    localobjectreference:
+      name: kafka-tls
+    key: tls.key
+    optional: true
+shutdowntimeout: 45s
+`,
+		`
+brokers: [kafka-broker:9092]
+topic: test-topic
+consumerGroupName: test-consumer-group
+tls:
+  insecureSkipVerify: true
+  certSecret: {localObjectReference: {name: kafka-tls}, key: tls.crt, optional: true}
+  keySecret: {localObjectReference: {name: kafka-tls}, key: tls.key, optional: true}
+shutdownTimeout: 45000000000
+`,
+		`
+brokers: [kafka-broker:9092]
+topic: test-topic
+consumerGroup: test-consumer-group
+tls:
+  insecureSkipVerify: true
+  clientCertSecret: {name: kafka-tls, key: tls.crt, optional: true}
+  clientKeySecret: {name: kafka-tls, key: tls.key, optional: true}
+shutdownTimeout: 45s
+`,
+	} {
+		c, err := (&YAMLConfigParser{}).Parse(doc)
+		assert.NoError(t, err, doc)
+		assert.Equal(t, want, c, doc)
+	}
+}
+
+func TestYAMLConfigParser_UnknownKeys(t *testing.T) {
+	_, err := (&YAMLConfigParser{}).Parse(`
+brokers: [kafka-broker:9092]
+topic: test-topic
+consumergroup_name: test-consumer-group
+sasl:
+  mechanism: PLAIN
+  plain:
+    usrSecret: {name: kafka-user, key: user}
+    password: {name: kafka-user, key: password}
+`)
+	assert.EqualError(t, err, `failed to parse config string: line 4: unknown key "consumergroup_name" in the config, did you mean "consumerGroupName"?
+line 8: unknown key "usrSecret" in sasl.plain, did you mean "userSecret"?
+line 9: unknown key "password" in sasl.plain`)
+}
+
+func TestYAMLConfigParser_DuplicatedKeys(t *testing.T) {
+	_, err := (&YAMLConfigParser{}).Parse(`
+topic: test-topic
+consumerGroup: a
+consumergroupname: b
+tls:
+  cacertsecret:
+    localobjectreference: {name: kafka-tls}
+    name: kafka-tls
+    key: ca.crt
+`)
+	assert.EqualError(t, err, `failed to parse config string: line 4: key "consumergroupname" sets the same field as "consumerGroup" on line 3
+line 8: key "name" can't be set together with "localobjectreference" on line 7`)
+}
+
+func TestYAMLConfigParser_Anchors(t *testing.T) {
+	c, err := (&YAMLConfigParser{}).Parse(`
+topic: test-topic
+tls:
+  clientCertSecret: &tls {name: kafka-tls, key: tls.crt}
+  clientKeySecret:
+    <<: *tls
+    key: tls.key
+dedup: {by: key, window: 600000000000}
+`)
+	assert.NoError(t, err)
+	assert.Equal(t, "kafka-tls", c.TLS.CertSecret.Name)
+	assert.Equal(t, "tls.crt", c.TLS.CertSecret.Key)
+	assert.Equal(t, "kafka-tls", c.TLS.KeySecret.Name)
+	assert.Equal(t, "tls.key", c.TLS.KeySecret.Key)
+	assert.Equal(t, 10*time.Minute, c.Dedup.Window)
+}
+
+func TestYAMLConfigParser_TypeErrorsHaveLines(t *testing.T) {
+	_, err := (&YAMLConfigParser{}).Parse("topic: test-topic\nhealth:\n  errorThreshold: many\n")
+	assert.ErrorContains(t, err, "line 3: cannot unmarshal !!str `many` into int")
+
+	c, err := (&YAMLConfigParser{}).Parse("")
+	assert.NoError(t, err)
+	assert.Equal(t, &Config{}, c)
+}
+
+func TestLowerCamel(t *testing.T) {
+	for name, want := range map[string]string{
+		"TLS":                "tls",
+		"CACertSecret":       "caCertSecret",
+		"MessageIDHeader":    "messageIDHeader",
+		"InsecureSkipVerify": "insecureSkipVerify",
+		"GSSAPI":             "gssapi",
+	} {
+		assert.Equal(t, want, lowerCamel(name))
+	}
+}
@@ -0,0 +1,37 @@
+package health
+
+import (
+	"net/http"
+)
+
+const (
+	// LivenessPath is the path of the liveness probe.
+	LivenessPath = "/livez"
+	// ReadinessPath is the path of the readiness probe.
+	ReadinessPath = "/readyz"
+)
+
+// Prober reports whether a component is live and ready, a nil error meaning it is.
+type Prober interface {
+	Live() error
+	Ready() error
+}
+
+// Handler serves a probe, it responds with 200 if check succeeds and with 503 and the error otherwise.
+func Handler(check func() error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := check(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(err.Error() + "\n"))
+			return
+		}
+		_, _ = w.Write([]byte("ok\n"))
+	})
+}
+
+// Register serves the liveness and readiness probes of p on mux.
+func Register(mux interface{ Handle(string, http.Handler) }, p Prober) {
+	mux.Handle(LivenessPath, Handler(p.Live))
+	mux.Handle(ReadinessPath, Handler(p.Ready))
+}
@@ -0,0 +1,47 @@
+package health
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type prober struct {
+	live, ready error
+}
+
+func (p *prober) Live() error  { return p.live }
+func (p *prober) Ready() error { return p.ready }
+
+func get(t *testing.T, h http.Handler, path string) (int, string) {
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+	resp, err := http.Get(ts.URL + path)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestRegister(t *testing.T) {
+	mux := http.NewServeMux()
+	p := &prober{ready: errors.New("no partitions assigned")}
+	Register(mux, p)
+
+	code, body := get(t, mux, LivenessPath)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok\n", body)
+
+	code, body = get(t, mux, ReadinessPath)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "no partitions assigned\n", body)
+
+	p.ready = nil
+	code, _ = get(t, mux, ReadinessPath)
+	assert.Equal(t, http.StatusOK, code)
+}
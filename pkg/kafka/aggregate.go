@@ -0,0 +1,167 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+const (
+	defaultAggregateMaxRecords = 100
+	defaultAggregateMaxBytes   = 1024 * 1024
+)
+
+// aggregator packs the messages of every partition into batches of bounded count and size.
+type aggregator struct {
+	maxRecords int
+	maxBytes   int
+	encode     func(values [][]byte) ([]byte, error)
+
+	lock    sync.Mutex
+	batches map[int32]*batch
+}
+
+// batch holds the messages of a partition waiting to be packed into one aggregated message.
+type batch struct {
+	topic     string
+	partition int32
+	values    [][]byte
+	offsets   []int64
+	size      int
+	eventTime time.Time
+}
+
+func newAggregator(c *config.Aggregate) (*aggregator, error) {
+	a := &aggregator{
+		maxRecords: c.MaxRecords,
+		maxBytes:   c.MaxBytes,
+		batches:    make(map[int32]*batch),
+	}
+	if a.maxRecords <= 0 {
+		a.maxRecords = defaultAggregateMaxRecords
+	}
+	if a.maxBytes <= 0 {
+		a.maxBytes = defaultAggregateMaxBytes
+	}
+	switch c.Format {
+	case config.AggregateFormatJSONArray:
+		a.encode = encodeJSONArray
+	case config.AggregateFormatLengthPrefixed:
+		a.encode = encodeLengthPrefixed
+	default:
+		return nil, fmt.Errorf("failed to parse aggregate format %q. Must be one of the following: ['%s', '%s']", c.Format, config.AggregateFormatJSONArray, config.AggregateFormatLengthPrefixed)
+	}
+	return a, nil
+}
+
+// add appends the value of a message read at the offset of a partition to the batch of the partition. It returns the
+// batches that are full.
+func (a *aggregator) add(topic string, partition int32, offset int64, value []byte, eventTime time.Time) []*batch {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	var full []*batch
+	b, ok := a.batches[partition]
+	if ok && b.size+len(value) > a.maxBytes {
+		// the value doesn't fit, the batch is emitted without it.
+		full = append(full, b)
+		ok = false
+	}
+	if !ok {
+		b = &batch{
+			topic:     topic,
+			partition: partition,
+			eventTime: eventTime,
+		}
+		a.batches[partition] = b
+	}
+	b.values = append(b.values, value)
+	b.offsets = append(b.offsets, offset)
+	b.size += len(value)
+	if eventTime.Before(b.eventTime) {
+		b.eventTime = eventTime
+	}
+	if len(b.values) >= a.maxRecords || b.size >= a.maxBytes {
+		full = append(full, b)
+		delete(a.batches, partition)
+	}
+	return full
+}
+
+// flush returns all the partially filled batches, ordered by partition.
+func (a *aggregator) flush() []*batch {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	result := make([]*batch, 0, len(a.batches))
+	for p, b := range a.batches {
+		result = append(result, b)
+		delete(a.batches, p)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].partition < result[j].partition
+	})
+	return result
+}
+
+// retain drops the batches of the partitions that are no longer assigned, after a rebalance.
+// Their records will be read again by the consumer the partitions are assigned to.
+func (a *aggregator) retain(partitions []int32) {
+	assigned := make(map[int32]struct{}, len(partitions))
+	for _, p := range partitions {
+		assigned[p] = struct{}{}
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for p := range a.batches {
+		if _, ok := assigned[p]; !ok {
+			delete(a.batches, p)
+		}
+	}
+}
+
+// highestOffset returns the highest offset in the batch, and the other ones.
+func (b *batch) highestOffset() (int64, []int64) {
+	highest := 0
+	for i, o := range b.offsets {
+		if o > b.offsets[highest] {
+			highest = i
+		}
+	}
+	others := make([]int64, 0, len(b.offsets)-1)
+	others = append(others, b.offsets[:highest]...)
+	others = append(others, b.offsets[highest+1:]...)
+	return b.offsets[highest], others
+}
+
+func encodeJSONArray(values [][]byte) ([]byte, error) {
+	elements := make([]json.RawMessage, 0, len(values))
+	for _, v := range values {
+		if json.Valid(v) {
+			elements = append(elements, v)
+			continue
+		}
+		s, err := json.Marshal(string(v))
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, s)
+	}
+	return json.Marshal(elements)
+}
+
+func encodeLengthPrefixed(values [][]byte) ([]byte, error) {
+	size := 0
+	for _, v := range values {
+		size += 4 + len(v)
+	}
+	result := make([]byte, 0, size)
+	for _, v := range values {
+		result = binary.BigEndian.AppendUint32(result, uint32(len(v)))
+		result = append(result, v...)
+	}
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+func TestAggregator_MaxRecords(t *testing.T) {
+	a, err := newAggregator(&config.Aggregate{Format: config.AggregateFormatJSONArray, MaxRecords: 2})
+	assert.NoError(t, err)
+	now := time.Now()
+	assert.Empty(t, a.add("test-topic", 0, 1, []byte(`{"a":1}`), now))
+	assert.Empty(t, a.add("test-topic", 1, 1, []byte(`x`), now))
+	full := a.add("test-topic", 0, 2, []byte(`plain`), now.Add(-time.Second))
+	assert.Len(t, full, 1)
+	assert.Equal(t, []int64{1, 2}, full[0].offsets)
+	// the earliest event time is kept
+	assert.Equal(t, now.Add(-time.Second), full[0].eventTime)
+	value, err := a.encode(full[0].values)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"a":1},"plain"]`, string(value))
+
+	flushed := a.flush()
+	assert.Len(t, flushed, 1)
+	assert.Equal(t, int32(1), flushed[0].partition)
+	assert.Empty(t, a.flush())
+}
+
+func TestAggregator_MaxBytes(t *testing.T) {
+	a, err := newAggregator(&config.Aggregate{Format: config.AggregateFormatLengthPrefixed, MaxBytes: 5})
+	assert.NoError(t, err)
+	now := time.Now()
+	assert.Empty(t, a.add("test-topic", 0, 1, []byte("ab"), now))
+	assert.Empty(t, a.add("test-topic", 0, 2, []byte("cd"), now))
+	// "efg" doesn't fit, the batch is emitted without it
+	full := a.add("test-topic", 0, 3, []byte("efg"), now)
+	assert.Len(t, full, 1)
+	value, err := a.encode(full[0].values)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 0, 2, 'a', 'b', 0, 0, 0, 2, 'c', 'd'}, value)
+	// a value larger than max bytes is emitted alone
+	full = a.add("test-topic", 0, 4, []byte("hijklm"), now)
+	assert.Len(t, full, 2)
+	assert.Equal(t, []int64{3}, full[0].offsets)
+	assert.Equal(t, []int64{4}, full[1].offsets)
+}
+
+func TestAggregator_RetainAndHighestOffset(t *testing.T) {
+	a, err := newAggregator(&config.Aggregate{Format: config.AggregateFormatJSONArray})
+	assert.NoError(t, err)
+	now := time.Now()
+	a.add("test-topic", 0, 7, []byte("1"), now)
+	a.add("test-topic", 0, 9, []byte("2"), now)
+	a.add("test-topic", 0, 8, []byte("3"), now)
+	a.add("test-topic", 1, 1, []byte("1"), now)
+	a.retain([]int32{0})
+	flushed := a.flush()
+	assert.Len(t, flushed, 1)
+	highest, others := flushed[0].highestOffset()
+	assert.Equal(t, int64(9), highest)
+	assert.Equal(t, []int64{7, 8}, others)
+
+	_, err = newAggregator(&config.Aggregate{Format: "avro"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,217 @@
+package kafka
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+const (
+	defaultChunkMessageIDHeader = "chunk-message-id"
+	defaultChunkIndexHeader     = "chunk-index"
+	defaultChunkTotalHeader     = "chunk-total"
+	defaultChunkTimeout         = time.Minute
+	defaultChunkMaxBufferBytes  = 64 * 1024 * 1024
+	// maxChunkTotal bounds the number of chunks of a message, so that an invalid total header doesn't allocate them all.
+	maxChunkTotal = 10000
+)
+
+// reassembler buffers the chunks of large messages until all of them are read, and joins them into a single message.
+type reassembler struct {
+	idHeader       string
+	indexHeader    string
+	totalHeader    string
+	timeout        time.Duration
+	maxBufferBytes int
+	now            func() time.Time
+
+	lock sync.Mutex
+	// incomplete messages in the order their first chunk was read
+	order   *list.List
+	pending map[chunkKey]*list.Element
+	// size of the buffered chunks
+	bufferBytes int
+}
+
+type chunkKey struct {
+	partition int32
+	id        string
+}
+
+// chunkedMessage is a message whose chunks are being read.
+type chunkedMessage struct {
+	key       chunkKey
+	topic     string
+	chunks    []*sarama.ConsumerMessage
+	received  int
+	size      int
+	startedAt time.Time
+	// offsets of every chunk read for the message, including duplicated ones
+	offsets []int64
+}
+
+func newReassembler(c *config.Chunking) *reassembler {
+	r := &reassembler{
+		idHeader:       c.MessageIDHeader,
+		indexHeader:    c.IndexHeader,
+		totalHeader:    c.TotalHeader,
+		timeout:        c.Timeout,
+		maxBufferBytes: c.MaxBufferBytes,
+		now:            time.Now,
+		order:          list.New(),
+		pending:        make(map[chunkKey]*list.Element),
+	}
+	if r.idHeader == "" {
+		r.idHeader = defaultChunkMessageIDHeader
+	}
+	if r.indexHeader == "" {
+		r.indexHeader = defaultChunkIndexHeader
+	}
+	if r.totalHeader == "" {
+		r.totalHeader = defaultChunkTotalHeader
+	}
+	if r.timeout <= 0 {
+		r.timeout = defaultChunkTimeout
+	}
+	if r.maxBufferBytes <= 0 {
+		r.maxBufferBytes = defaultChunkMaxBufferBytes
+	}
+	return r
+}
+
+// add takes a record read from the topic. Records that are not chunks are returned as is. The chunks are buffered
+// until the last one of a message is read, which returns the reassembled message along with the offsets of the other
+// chunks, to be acked together with it. The reassembled message has the offset of the chunk that completed it.
+// A nil message means the record was buffered.
+func (r *reassembler) add(m *sarama.ConsumerMessage) (*sarama.ConsumerMessage, []int64, error) {
+	id, ok := header(m, r.idHeader)
+	if !ok {
+		return m, nil, nil
+	}
+	index, err := intHeader(m, r.indexHeader)
+	if err != nil {
+		return m, nil, err
+	}
+	total, err := intHeader(m, r.totalHeader)
+	if err != nil {
+		return m, nil, err
+	}
+	if total <= 0 || index < 0 || index >= total {
+		return m, nil, fmt.Errorf("invalid chunk %d of %d for message %s", index, total, id)
+	}
+	if total > maxChunkTotal {
+		return m, nil, fmt.Errorf("chunk %d of message %s has a total of %d, more than the maximum of %d", index, id, total, maxChunkTotal)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	key := chunkKey{partition: m.Partition, id: string(id)}
+	e, ok := r.pending[key]
+	if !ok {
+		e = r.order.PushBack(&chunkedMessage{
+			key:       key,
+			topic:     m.Topic,
+			chunks:    make([]*sarama.ConsumerMessage, total),
+			startedAt: r.now(),
+		})
+		r.pending[key] = e
+	}
+	cm := e.Value.(*chunkedMessage)
+	if len(cm.chunks) != total {
+		return m, nil, fmt.Errorf("chunk %d of message %s has a total of %d, expected %d", index, id, total, len(cm.chunks))
+	}
+	cm.offsets = append(cm.offsets, m.Offset)
+	if cm.chunks[index] == nil {
+		cm.chunks[index] = m
+		cm.received++
+		cm.size += len(m.Value)
+		r.bufferBytes += len(m.Value)
+	}
+	if cm.received < total {
+		return nil, nil, nil
+	}
+
+	r.remove(e)
+	value := make([][]byte, 0, total)
+	for _, c := range cm.chunks {
+		value = append(value, c.Value)
+	}
+	first := cm.chunks[0]
+	assembled := &sarama.ConsumerMessage{
+		Headers:        first.Headers,
+		Timestamp:      first.Timestamp,
+		BlockTimestamp: first.BlockTimestamp,
+		Key:            first.Key,
+		Value:          bytes.Join(value, nil),
+		Topic:          m.Topic,
+		Partition:      m.Partition,
+		Offset:         m.Offset,
+	}
+	members := make([]int64, 0, len(cm.offsets)-1)
+	for _, o := range cm.offsets {
+		if o != m.Offset {
+			members = append(members, o)
+		}
+	}
+	return assembled, members, nil
+}
+
+// expire drops the incomplete messages that waited longer than the timeout, as well as the oldest ones while the
+// buffered chunks exceed the memory cap. The dropped messages are returned so that their chunks can be acked.
+func (r *reassembler) expire() []*chunkedMessage {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var dropped []*chunkedMessage
+	now := r.now()
+	for e := r.order.Front(); e != nil; e = r.order.Front() {
+		cm := e.Value.(*chunkedMessage)
+		if now.Sub(cm.startedAt) <= r.timeout && r.bufferBytes <= r.maxBufferBytes {
+			break
+		}
+		r.remove(e)
+		dropped = append(dropped, cm)
+	}
+	return dropped
+}
+
+// retain drops the incomplete messages of the partitions that are no longer assigned, after a rebalance.
+// Their chunks will be read again by the consumer the partitions are assigned to.
+func (r *reassembler) retain(partitions []int32) {
+	assigned := make(map[int32]struct{}, len(partitions))
+	for _, p := range partitions {
+		assigned[p] = struct{}{}
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for key, e := range r.pending {
+		if _, ok := assigned[key.partition]; !ok {
+			r.remove(e)
+		}
+	}
+}
+
+func (r *reassembler) remove(e *list.Element) {
+	cm := e.Value.(*chunkedMessage)
+	r.order.Remove(e)
+	delete(r.pending, cm.key)
+	r.bufferBytes -= cm.size
+}
+
+func intHeader(m *sarama.ConsumerMessage, name string) (int, error) {
+	v, ok := header(m, name)
+	if !ok {
+		return 0, fmt.Errorf("header %s is missing", name)
+	}
+	i, err := strconv.Atoi(string(v))
+	if err != nil {
+		return 0, fmt.Errorf("invalid header %s value %q, %w", name, v, err)
+	}
+	return i, nil
+}
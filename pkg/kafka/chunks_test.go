@@ -0,0 +1,88 @@
+package kafka
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+func chunk(id string, index, total int, offset int64, value string) *sarama.ConsumerMessage {
+	return &sarama.ConsumerMessage{
+		Topic:  "test-topic",
+		Offset: offset,
+		Value:  []byte(value),
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte(defaultChunkMessageIDHeader), Value: []byte(id)},
+			{Key: []byte(defaultChunkIndexHeader), Value: []byte(strconv.Itoa(index))},
+			{Key: []byte(defaultChunkTotalHeader), Value: []byte(strconv.Itoa(total))},
+		},
+	}
+}
+
+func TestReassembler_Add(t *testing.T) {
+	r := newReassembler(&config.Chunking{})
+
+	plain := &sarama.ConsumerMessage{Offset: 1, Value: []byte("plain")}
+	m, members, err := r.add(plain)
+	assert.NoError(t, err)
+	assert.Equal(t, plain, m)
+	assert.Empty(t, members)
+
+	m, _, err = r.add(chunk("a", 1, 3, 2, "world"))
+	assert.NoError(t, err)
+	assert.Nil(t, m)
+	m, _, err = r.add(chunk("a", 0, 3, 3, "hello "))
+	assert.NoError(t, err)
+	assert.Nil(t, m)
+	// duplicated chunk
+	m, _, err = r.add(chunk("a", 0, 3, 4, "hello "))
+	assert.NoError(t, err)
+	assert.Nil(t, m)
+	m, members, err = r.add(chunk("a", 2, 3, 5, "!"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world!", string(m.Value))
+	assert.Equal(t, int64(5), m.Offset)
+	assert.ElementsMatch(t, []int64{2, 3, 4}, members)
+	assert.Equal(t, 0, r.bufferBytes)
+}
+
+func TestReassembler_InvalidChunk(t *testing.T) {
+	r := newReassembler(&config.Chunking{})
+	invalid := chunk("a", 3, 3, 1, "x")
+	m, _, err := r.add(invalid)
+	assert.Error(t, err)
+	assert.Equal(t, invalid, m)
+
+	// the total is checked before the chunks are allocated
+	huge := chunk("b", 0, math.MaxInt32, 2, "x")
+	m, _, err = r.add(huge)
+	assert.ErrorContains(t, err, "more than the maximum of 10000")
+	assert.Equal(t, huge, m)
+	assert.Empty(t, r.pending)
+}
+
+func TestReassembler_Expire(t *testing.T) {
+	r := newReassembler(&config.Chunking{Timeout: time.Minute, MaxBufferBytes: 4})
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	_, _, _ = r.add(chunk("a", 0, 2, 1, "aa"))
+	assert.Empty(t, r.expire())
+	_, _, _ = r.add(chunk("b", 0, 2, 2, "bbb"))
+	// over the memory cap, the oldest message is dropped
+	dropped := r.expire()
+	assert.Len(t, dropped, 1)
+	assert.Equal(t, "a", dropped[0].key.id)
+	assert.Equal(t, []int64{1}, dropped[0].offsets)
+
+	now = now.Add(2 * time.Minute)
+	dropped = r.expire()
+	assert.Len(t, dropped, 1)
+	assert.Equal(t, "b", dropped[0].key.id)
+}
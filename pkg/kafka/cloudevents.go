@@ -0,0 +1,193 @@
+package kafka
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	sourcesdk "github.com/numaproj/numaflow-go/pkg/sourcer"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+const (
+	// cloudEventsHeaderPrefix prefixes the CloudEvent attributes in binary mode.
+	cloudEventsHeaderPrefix = "ce_"
+	// cloudEventsContentType is the content type of a CloudEvent in structured mode.
+	cloudEventsContentType = "application/cloudevents+json"
+	contentTypeHeader      = "content-type"
+)
+
+// errNotCloudEvent is returned for records that are neither in binary nor in structured mode.
+var errNotCloudEvent = errors.New("record is not a cloud event")
+
+var defaultCloudEventKeys = []string{"type", "subject"}
+
+// traceContextHeaders are the W3C trace context headers carried by the distributed tracing extension of the events.
+var traceContextHeaders = []string{"traceparent", "tracestate"}
+
+// cloudEventsDecoder normalises the CloudEvents read in binary and in structured mode to the JSON event format.
+type cloudEventsDecoder struct {
+	keys []string
+}
+
+func newCloudEventsDecoder(c *config.CloudEvents) *cloudEventsDecoder {
+	d := &cloudEventsDecoder{
+		keys: c.Keys,
+	}
+	if len(d.keys) == 0 {
+		d.keys = defaultCloudEventKeys
+	}
+	return d
+}
+
+// cloudEvent is a CloudEvent in the JSON event format, the attributes are kept as raw JSON values.
+type cloudEvent map[string]json.RawMessage
+
+// decode reads m as a CloudEvent, it returns errNotCloudEvent if m is neither in binary nor in structured mode.
+func (d *cloudEventsDecoder) decode(m *sarama.ConsumerMessage) (cloudEvent, error) {
+	var ce cloudEvent
+	var err error
+	if contentType, ok := header(m, contentTypeHeader); ok && isCloudEventsContentType(string(contentType)) {
+		ce, err = decodeStructured(m)
+	} else if _, ok := header(m, cloudEventsHeaderPrefix+"specversion"); ok {
+		ce, err = decodeBinary(m)
+	} else {
+		return nil, errNotCloudEvent
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, required := range []string{"specversion", "id", "source", "type"} {
+		if _, ok := ce.attribute(required); !ok {
+			return nil, fmt.Errorf("cloud event is missing the required attribute %s", required)
+		}
+	}
+	if t, ok := ce.attribute("time"); ok {
+		if _, err := time.Parse(time.RFC3339Nano, t); err != nil {
+			return nil, fmt.Errorf("invalid cloud event time %q, %w", t, err)
+		}
+	}
+	// the trace context of the record is carried downstream by the distributed tracing extension, unless the event
+	// already has its own.
+	for _, name := range traceContextHeaders {
+		if _, ok := ce[name]; ok {
+			continue
+		}
+		if v, ok := header(m, name); ok {
+			if ce[name], err = json.Marshal(string(v)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ce, nil
+}
+
+// toSDKMessage converts a record to an SDK message in the JSON event format, with the CloudEvent time as event time
+// and the configured attributes as keys.
+func (d *cloudEventsDecoder) toSDKMessage(m *sarama.ConsumerMessage, offset sourcesdk.Offset) (sourcesdk.Message, error) {
+	ce, err := d.decode(m)
+	if err != nil {
+		return sourcesdk.Message{}, err
+	}
+	value, err := json.Marshal(ce)
+	if err != nil {
+		return sourcesdk.Message{}, fmt.Errorf("failed to encode cloud event, %w", err)
+	}
+	eventTime := m.Timestamp
+	if t, ok := ce.attribute("time"); ok {
+		eventTime, _ = time.Parse(time.RFC3339Nano, t)
+	}
+	var keys []string
+	for _, k := range d.keys {
+		if v, ok := ce.attribute(k); ok {
+			keys = append(keys, v)
+		}
+	}
+	return sourcesdk.NewMessage(value, offset, eventTime).WithKeys(keys), nil
+}
+
+// attribute returns the value of a string attribute.
+func (ce cloudEvent) attribute(name string) (string, bool) {
+	raw, ok := ce[name]
+	if !ok {
+		return "", false
+	}
+	var v string
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return "", false
+	}
+	return v, true
+}
+
+func decodeStructured(m *sarama.ConsumerMessage) (cloudEvent, error) {
+	ce := cloudEvent{}
+	if err := json.Unmarshal(m.Value, &ce); err != nil {
+		return nil, fmt.Errorf("failed to decode structured cloud event, %w", err)
+	}
+	return ce, nil
+}
+
+func decodeBinary(m *sarama.ConsumerMessage) (cloudEvent, error) {
+	ce := cloudEvent{}
+	set := func(name string, value []byte) error {
+		v, err := json.Marshal(string(value))
+		if err != nil {
+			return err
+		}
+		ce[name] = v
+		return nil
+	}
+	for _, h := range m.Headers {
+		if h == nil {
+			continue
+		}
+		name := strings.ToLower(string(h.Key))
+		switch {
+		case strings.HasPrefix(name, cloudEventsHeaderPrefix):
+			if err := set(strings.TrimPrefix(name, cloudEventsHeaderPrefix), h.Value); err != nil {
+				return nil, err
+			}
+		case name == contentTypeHeader:
+			if err := set("datacontenttype", h.Value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if m.Value == nil {
+		return ce, nil
+	}
+	if contentType, _ := ce.attribute("datacontenttype"); isJSONContentType(contentType) && json.Valid(m.Value) {
+		ce["data"] = m.Value
+		return ce, nil
+	}
+	data, err := json.Marshal(base64.StdEncoding.EncodeToString(m.Value))
+	if err != nil {
+		return nil, err
+	}
+	ce["data_base64"] = data
+	return ce, nil
+}
+
+func isCloudEventsContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == cloudEventsContentType
+}
+
+// isJSONContentType reports whether the data of a binary mode event is JSON, in which case it is embedded as is.
+// The data is assumed to be JSON if the content type is not set.
+func isJSONContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
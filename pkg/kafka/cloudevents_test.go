@@ -0,0 +1,94 @@
+package kafka
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+func headers(kv ...string) []*sarama.RecordHeader {
+	var result []*sarama.RecordHeader
+	for i := 0; i+1 < len(kv); i += 2 {
+		result = append(result, &sarama.RecordHeader{Key: []byte(kv[i]), Value: []byte(kv[i+1])})
+	}
+	return result
+}
+
+func TestCloudEventsDecoder_BinaryAndStructuredAreNormalised(t *testing.T) {
+	d := newCloudEventsDecoder(&config.CloudEvents{})
+	binary := &sarama.ConsumerMessage{
+		Topic: "test-topic",
+		Headers: headers(
+			"ce_specversion", "1.0",
+			"ce_id", "1",
+			"ce_source", "/orders",
+			"ce_type", "order.created",
+			"ce_subject", "order-1",
+			"ce_time", "2023-09-01T10:00:00Z",
+			"content-type", "application/json",
+		),
+		Value: []byte(`{"amount":10}`),
+	}
+	structured := &sarama.ConsumerMessage{
+		Topic:   "test-topic",
+		Headers: headers("content-type", "application/cloudevents+json; charset=utf-8"),
+		Value:   []byte(`{"specversion":"1.0","id":"1","source":"/orders","type":"order.created","subject":"order-1","time":"2023-09-01T10:00:00Z","datacontenttype":"application/json","data":{"amount":10}}`),
+	}
+
+	fromBinary, err := d.toSDKMessage(binary, GenerateSourceSdkOffset(binary, unknownLeaderEpoch))
+	assert.NoError(t, err)
+	fromStructured, err := d.toSDKMessage(structured, GenerateSourceSdkOffset(structured, unknownLeaderEpoch))
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(fromStructured.Value()), string(fromBinary.Value()))
+	assert.Equal(t, []string{"order.created", "order-1"}, fromBinary.Keys())
+	assert.Equal(t, fromStructured.Keys(), fromBinary.Keys())
+	assert.Equal(t, time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC), fromBinary.EventTime())
+	assert.Equal(t, fromStructured.EventTime(), fromBinary.EventTime())
+}
+
+func TestCloudEventsDecoder_BinaryDataBase64(t *testing.T) {
+	d := newCloudEventsDecoder(&config.CloudEvents{Keys: []string{"subject"}})
+	ts := time.Now()
+	m := &sarama.ConsumerMessage{
+		Headers: headers(
+			"ce_specversion", "1.0",
+			"ce_id", "1",
+			"ce_source", "/orders",
+			"ce_type", "order.created",
+			"content-type", "application/octet-stream",
+		),
+		Value:     []byte{0x01, 0x02},
+		Timestamp: ts,
+	}
+	msg, err := d.toSDKMessage(m, GenerateSourceSdkOffset(m, unknownLeaderEpoch))
+	assert.NoError(t, err)
+	ce := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(msg.Value(), &ce))
+	assert.Equal(t, "AQI=", ce["data_base64"])
+	// the record timestamp is used without a cloud event time
+	assert.Equal(t, ts, msg.EventTime())
+	// subject is missing
+	assert.Empty(t, msg.Keys())
+}
+
+func TestCloudEventsDecoder_Errors(t *testing.T) {
+	d := newCloudEventsDecoder(&config.CloudEvents{})
+	_, err := d.decode(&sarama.ConsumerMessage{Value: []byte("plain")})
+	assert.ErrorIs(t, err, errNotCloudEvent)
+
+	_, err = d.decode(&sarama.ConsumerMessage{
+		Headers: headers("content-type", "application/cloudevents+json"),
+		Value:   []byte(`{"specversion":"1.0","id":"1","source":"/orders"}`),
+	})
+	assert.ErrorContains(t, err, "type")
+
+	_, err = d.decode(&sarama.ConsumerMessage{
+		Headers: headers("ce_specversion", "1.0", "ce_id", "1", "ce_source", "/orders", "ce_type", "t", "ce_time", "yesterday"),
+	})
+	assert.ErrorContains(t, err, "invalid cloud event time")
+}
@@ -0,0 +1,130 @@
+package kafka
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+const defaultDedupMaxEntries = 10000
+
+// deduplicator remembers the identities of the records seen on every assigned partition within a bounded window.
+type deduplicator struct {
+	identify   func(m *sarama.ConsumerMessage) (string, bool)
+	window     time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	lock       sync.Mutex
+	partitions map[int32]*dedupWindow
+}
+
+// dedupWindow holds the identities seen on one partition, in the order they were first seen.
+type dedupWindow struct {
+	seen  map[string]*list.Element
+	order *list.List
+}
+
+type dedupEntry struct {
+	id     string
+	seenAt time.Time
+}
+
+func newDeduplicator(c *config.Dedup) (*deduplicator, error) {
+	d := &deduplicator{
+		window:     c.Window,
+		maxEntries: c.MaxEntries,
+		now:        time.Now,
+		partitions: make(map[int32]*dedupWindow),
+	}
+	if d.maxEntries <= 0 {
+		d.maxEntries = defaultDedupMaxEntries
+	}
+	switch c.By {
+	case config.DedupByKey:
+		d.identify = func(m *sarama.ConsumerMessage) (string, bool) {
+			return string(m.Key), m.Key != nil
+		}
+	case config.DedupByHeader:
+		if c.Header == "" {
+			return nil, fmt.Errorf("dedup header name is required when deduplicating by %s", config.DedupByHeader)
+		}
+		d.identify = func(m *sarama.ConsumerMessage) (string, bool) {
+			v, ok := header(m, c.Header)
+			return string(v), ok
+		}
+	case config.DedupByValueHash:
+		d.identify = func(m *sarama.ConsumerMessage) (string, bool) {
+			sum := sha256.Sum256(m.Value)
+			return string(sum[:]), true
+		}
+	default:
+		return nil, fmt.Errorf("failed to parse dedup by %q. Must be one of the following: ['%s', '%s', '%s']", c.By, config.DedupByKey, config.DedupByHeader, config.DedupByValueHash)
+	}
+	return d, nil
+}
+
+// isDuplicate reports whether the identity of m was already seen on its partition within the window, and remembers it
+// otherwise. Records without an identity are never duplicates.
+func (d *deduplicator) isDuplicate(m *sarama.ConsumerMessage) bool {
+	id, ok := d.identify(m)
+	if !ok {
+		return false
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	w, ok := d.partitions[m.Partition]
+	if !ok {
+		w = &dedupWindow{
+			seen:  make(map[string]*list.Element),
+			order: list.New(),
+		}
+		d.partitions[m.Partition] = w
+	}
+	now := d.now()
+	d.evict(w, now)
+	if _, ok := w.seen[id]; ok {
+		return true
+	}
+	w.seen[id] = w.order.PushBack(&dedupEntry{id: id, seenAt: now})
+	if w.order.Len() > d.maxEntries {
+		d.remove(w, w.order.Front())
+	}
+	return false
+}
+
+// evict drops the identities that fell out of the time window.
+func (d *deduplicator) evict(w *dedupWindow, now time.Time) {
+	if d.window <= 0 {
+		return
+	}
+	for e := w.order.Front(); e != nil && now.Sub(e.Value.(*dedupEntry).seenAt) > d.window; e = w.order.Front() {
+		d.remove(w, e)
+	}
+}
+
+func (d *deduplicator) remove(w *dedupWindow, e *list.Element) {
+	w.order.Remove(e)
+	delete(w.seen, e.Value.(*dedupEntry).id)
+}
+
+// retain drops the windows of the partitions that are no longer assigned, after a rebalance.
+func (d *deduplicator) retain(partitions []int32) {
+	assigned := make(map[int32]struct{}, len(partitions))
+	for _, p := range partitions {
+		assigned[p] = struct{}{}
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for p := range d.partitions {
+		if _, ok := assigned[p]; !ok {
+			delete(d.partitions, p)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	sourcesdk "github.com/numaproj/numaflow-go/pkg/sourcer"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+func TestDeduplicator_ByKey(t *testing.T) {
+	d, err := newDeduplicator(&config.Dedup{By: config.DedupByKey})
+	assert.NoError(t, err)
+	assert.False(t, d.isDuplicate(&sarama.ConsumerMessage{Key: []byte("a"), Partition: 0}))
+	assert.True(t, d.isDuplicate(&sarama.ConsumerMessage{Key: []byte("a"), Partition: 0}))
+	// windows are kept per partition
+	assert.False(t, d.isDuplicate(&sarama.ConsumerMessage{Key: []byte("a"), Partition: 1}))
+	// records without a key are never duplicates
+	assert.False(t, d.isDuplicate(&sarama.ConsumerMessage{Partition: 0}))
+	assert.False(t, d.isDuplicate(&sarama.ConsumerMessage{Partition: 0}))
+}
+
+func TestDeduplicator_ByHeader(t *testing.T) {
+	d, err := newDeduplicator(&config.Dedup{By: config.DedupByHeader, Header: "idempotency-key"})
+	assert.NoError(t, err)
+	withHeader := func(v string) *sarama.ConsumerMessage {
+		return &sarama.ConsumerMessage{Headers: []*sarama.RecordHeader{{Key: []byte("idempotency-key"), Value: []byte(v)}}}
+	}
+	assert.False(t, d.isDuplicate(withHeader("1")))
+	assert.True(t, d.isDuplicate(withHeader("1")))
+	assert.False(t, d.isDuplicate(withHeader("2")))
+
+	_, err = newDeduplicator(&config.Dedup{By: config.DedupByHeader})
+	assert.Error(t, err)
+}
+
+func TestDeduplicator_ByValueHash(t *testing.T) {
+	d, err := newDeduplicator(&config.Dedup{By: config.DedupByValueHash})
+	assert.NoError(t, err)
+	assert.False(t, d.isDuplicate(&sarama.ConsumerMessage{Value: []byte("payload")}))
+	assert.True(t, d.isDuplicate(&sarama.ConsumerMessage{Value: []byte("payload")}))
+	assert.False(t, d.isDuplicate(&sarama.ConsumerMessage{Value: []byte("other payload")}))
+
+	_, err = newDeduplicator(&config.Dedup{By: "unknown"})
+	assert.Error(t, err)
+}
+
+func TestDeduplicator_Window(t *testing.T) {
+	d, err := newDeduplicator(&config.Dedup{By: config.DedupByKey, Window: time.Minute, MaxEntries: 2})
+	assert.NoError(t, err)
+	now := time.Now()
+	d.now = func() time.Time { return now }
+
+	assert.False(t, d.isDuplicate(&sarama.ConsumerMessage{Key: []byte("a")}))
+	now = now.Add(2 * time.Minute)
+	// "a" fell out of the time window
+	assert.False(t, d.isDuplicate(&sarama.ConsumerMessage{Key: []byte("a")}))
+	assert.False(t, d.isDuplicate(&sarama.ConsumerMessage{Key: []byte("b")}))
+	assert.False(t, d.isDuplicate(&sarama.ConsumerMessage{Key: []byte("c")}))
+	// "a" fell out of the size window
+	assert.False(t, d.isDuplicate(&sarama.ConsumerMessage{Key: []byte("a")}))
+	assert.True(t, d.isDuplicate(&sarama.ConsumerMessage{Key: []byte("c")}))
+}
+
+func TestDeduplicator_Retain(t *testing.T) {
+	d, err := newDeduplicator(&config.Dedup{By: config.DedupByKey})
+	assert.NoError(t, err)
+	assert.False(t, d.isDuplicate(&sarama.ConsumerMessage{Key: []byte("a"), Partition: 0}))
+	assert.False(t, d.isDuplicate(&sarama.ConsumerMessage{Key: []byte("a"), Partition: 1}))
+
+	d.retain([]int32{0})
+	assert.True(t, d.isDuplicate(&sarama.ConsumerMessage{Key: []byte("a"), Partition: 0}))
+	assert.False(t, d.isDuplicate(&sarama.ConsumerMessage{Key: []byte("a"), Partition: 1}))
+}
+
+func TestRead_DuplicatesDontCommitPastUnackedOffsets(t *testing.T) {
+	d, err := newDeduplicator(&config.Dedup{By: config.DedupByKey})
+	assert.NoError(t, err)
+	f := &fakeEpochs{current: 3}
+	sess := &markSession{}
+	k := &kafkaSource{
+		topic:   "test-topic",
+		handler: newConsumerHandler(3),
+		tracker: newOffsetTracker(),
+		epochs:  f.leaderEpochs(),
+		health:  newConsumerHealth(nil),
+		dedup:   d,
+		logger:  zap.NewNop(),
+	}
+	k.handler.sess = sess
+	for o, key := range []string{"a", "b", "a"} {
+		k.handler.messages <- &sarama.ConsumerMessage{Topic: "test-topic", Key: []byte(key), Offset: int64(o)}
+	}
+
+	messageCh := make(chan sourcesdk.Message, 3)
+	k.Read(context.Background(), readRequest{count: 3, timeout: 50 * time.Millisecond}, messageCh)
+	assert.Len(t, messageCh, 2)
+	// the duplicate is acked, but the records before it are not yet
+	assert.Empty(t, sess.marked)
+
+	offsets := make([]sourcesdk.Offset, 0, 2)
+	for len(messageCh) > 0 {
+		offsets = append(offsets, (<-messageCh).Offset())
+	}
+	k.Ack(context.Background(), ackRequest(offsets))
+	// up to the duplicate
+	assert.Equal(t, int64(2), sess.marked[len(sess.marked)-1])
+}
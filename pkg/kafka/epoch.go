@@ -0,0 +1,158 @@
+package kafka
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/IBM/sarama"
+)
+
+// unknownLeaderEpoch is the leader epoch of the partitions whose metadata doesn't carry it, before Kafka 2.1.
+const unknownLeaderEpoch int32 = -1
+
+// leaderEpochMetadataFormat is the format of the metadata the offsets are committed with. The consumer group session
+// commits the leader epoch it fetched the offsets with, so the epoch the acked offsets exist under is recorded in the
+// metadata instead.
+const leaderEpochMetadataFormat = "leaderEpoch=%d"
+
+// leaderEpochs fences the acks of the offsets that no longer exist in the log of their partition. The leader epoch of a
+// partition is captured when its records are read. If the leader changed by the time a record is acked, e.g. after an
+// unclean leader election, the offset is checked against the log end offset of the new leader: the log was truncated
+// if the offset is beyond it, and the offset is not committed. The log end offset is captured as soon as the reads see
+// the new epoch, before the new leader appends many records: sarama doesn't implement OffsetForLeaderEpoch, which
+// returns the end offset of the old epoch in the log of the new leader.
+type leaderEpochs struct {
+	// current leader epoch of a partition, unknownLeaderEpoch if the brokers don't report it
+	current func(topic string, partition int32) (int32, error)
+	// log end offset of a partition
+	logEnd func(topic string, partition int32) (int64, error)
+
+	lock sync.Mutex
+	// last leader epoch the reads of a partition were under
+	seen map[topicPartition]int32
+	// log end offset of the partitions captured since their leader changed, with the epoch they were captured under
+	checked map[topicPartition]checkedEpoch
+	// highest leader epoch the acked offsets of the partitions are known to exist under
+	acked map[topicPartition]int32
+}
+
+type checkedEpoch struct {
+	epoch  int32
+	logEnd int64
+}
+
+func newLeaderEpochs(current func(topic string, partition int32) (int32, error), logEnd func(topic string, partition int32) (int64, error)) *leaderEpochs {
+	return &leaderEpochs{
+		current: current,
+		logEnd:  logEnd,
+		seen:    make(map[topicPartition]int32),
+		checked: make(map[topicPartition]checkedEpoch),
+		acked:   make(map[topicPartition]int32),
+	}
+}
+
+// at returns the current leader epoch of a partition, unknownLeaderEpoch if it can't be told. It captures the log end
+// offset of the partition when its epoch changed since the previous reads.
+func (e *leaderEpochs) at(topic string, partition int32) int32 {
+	epoch, err := e.current(topic, partition)
+	if err != nil || epoch < 0 {
+		return unknownLeaderEpoch
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	tp := topicPartition{topic: topic, partition: partition}
+	if seen, ok := e.seen[tp]; ok && seen < epoch {
+		// checked again with the acks if it fails.
+		_, _ = e.capture(tp, epoch)
+	}
+	e.seen[tp] = epoch
+	return epoch
+}
+
+// capture returns the log end offset of a partition under its current leader epoch, fetched the first time the epoch
+// is seen. It must be called with the lock held.
+func (e *leaderEpochs) capture(tp topicPartition, current int32) (checkedEpoch, error) {
+	if c, ok := e.checked[tp]; ok && c.epoch == current {
+		return c, nil
+	}
+	logEnd, err := e.logEnd(tp.topic, tp.partition)
+	if err != nil {
+		return checkedEpoch{}, err
+	}
+	c := checkedEpoch{epoch: current, logEnd: logEnd}
+	e.checked[tp] = c
+	return c, nil
+}
+
+// check returns an error if the offset was read under an older leader epoch and no longer exists in the log.
+func (e *leaderEpochs) check(o *KafkaOffset) error {
+	epoch, ok := o.LeaderEpoch()
+	if e == nil || !ok {
+		return nil
+	}
+	tp := topicPartition{topic: o.Topic(), partition: o.PartitionIdx()}
+	current := e.at(tp.topic, tp.partition)
+	if current == unknownLeaderEpoch || epoch >= current {
+		e.observe(tp, epoch)
+		return nil
+	}
+	// the log end offset was not captured if no records were read since the leader changed.
+	e.lock.Lock()
+	c, err := e.capture(tp, current)
+	e.lock.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to check offset %d read under leader epoch %d against leader epoch %d, %w", o.offset, epoch, current, err)
+	}
+	if o.offset >= c.logEnd {
+		return fmt.Errorf("offset %d read under leader epoch %d is beyond the log end offset %d of leader epoch %d, the log was truncated", o.offset, epoch, c.logEnd, current)
+	}
+	// the offset still exists under the current epoch.
+	e.observe(tp, current)
+	return nil
+}
+
+// observe records that the acked offsets of a partition exist under a leader epoch.
+func (e *leaderEpochs) observe(tp topicPartition, epoch int32) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if acked, ok := e.acked[tp]; !ok || epoch > acked {
+		e.acked[tp] = epoch
+	}
+}
+
+// metadata returns the metadata the offsets of a partition are committed with.
+func (e *leaderEpochs) metadata(topic string, partition int32) string {
+	if e == nil {
+		return ""
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if epoch, ok := e.acked[topicPartition{topic: topic, partition: partition}]; ok {
+		return fmt.Sprintf(leaderEpochMetadataFormat, epoch)
+	}
+	return ""
+}
+
+// leaderEpoch returns the leader epoch the records of a partition are read under.
+func (k *kafkaSource) leaderEpoch(topic string, partition int32) int32 {
+	if k.epochs == nil {
+		return unknownLeaderEpoch
+	}
+	return k.epochs.at(topic, partition)
+}
+
+func (k *kafkaSource) currentLeaderEpoch(topic string, partition int32) (int32, error) {
+	// the metadata carries the leader epochs from Kafka 2.1.
+	if k.saramaClient == nil || !k.config.Version.IsAtLeast(sarama.V2_1_0_0) {
+		return unknownLeaderEpoch, nil
+	}
+	_, epoch, err := k.saramaClient.LeaderAndEpoch(topic, partition)
+	return epoch, err
+}
+
+func (k *kafkaSource) logEndOffset(topic string, partition int32) (int64, error) {
+	if k.saramaClient == nil {
+		return 0, sarama.ErrClosedClient
+	}
+	return k.saramaClient.GetOffset(topic, partition, sarama.OffsetNewest)
+}
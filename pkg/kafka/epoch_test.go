@@ -0,0 +1,114 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/IBM/sarama"
+	sourcesdk "github.com/numaproj/numaflow-go/pkg/sourcer"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type fakeEpochs struct {
+	current      int32
+	logEnd       int64
+	logEndErr    error
+	logEndChecks int
+}
+
+func (f *fakeEpochs) leaderEpochs() *leaderEpochs {
+	return newLeaderEpochs(
+		func(string, int32) (int32, error) { return f.current, nil },
+		func(string, int32) (int64, error) {
+			f.logEndChecks++
+			return f.logEnd, f.logEndErr
+		},
+	)
+}
+
+func TestLeaderEpochs_Check(t *testing.T) {
+	f := &fakeEpochs{current: 3, logEnd: 150}
+	e := f.leaderEpochs()
+
+	// offsets without a leader epoch are not checked
+	assert.NoError(t, e.check(newKafkaOffset("test-topic", 0, 200, unknownLeaderEpoch)))
+	assert.Equal(t, "", e.metadata("test-topic", 0))
+
+	assert.NoError(t, e.check(newKafkaOffset("test-topic", 0, 100, 3)))
+	assert.Equal(t, "leaderEpoch=3", e.metadata("test-topic", 0))
+	assert.Equal(t, 0, f.logEndChecks)
+
+	// the leader changed since the records were read
+	f.current = 4
+	assert.NoError(t, e.check(newKafkaOffset("test-topic", 0, 120, 3)))
+	assert.Equal(t, "leaderEpoch=4", e.metadata("test-topic", 0))
+	err := e.check(newKafkaOffset("test-topic", 0, 160, 3))
+	assert.ErrorContains(t, err, "offset 160 read under leader epoch 3 is beyond the log end offset 150 of leader epoch 4")
+	// the log end offset is checked once per leader epoch
+	assert.Equal(t, 1, f.logEndChecks)
+	assert.Equal(t, "", e.metadata("test-topic", 1))
+}
+
+func TestLeaderEpochs_CapturesLogEndOnEpochChange(t *testing.T) {
+	f := &fakeEpochs{current: 3, logEnd: 150}
+	e := f.leaderEpochs()
+	assert.Equal(t, int32(3), e.at("test-topic", 0))
+	assert.Equal(t, 0, f.logEndChecks)
+
+	// the reads see the new leader before it appends more records
+	f.current = 4
+	assert.Equal(t, int32(4), e.at("test-topic", 0))
+	assert.Equal(t, 1, f.logEndChecks)
+	f.logEnd = 200
+	err := e.check(newKafkaOffset("test-topic", 0, 160, 3))
+	assert.ErrorContains(t, err, "beyond the log end offset 150 of leader epoch 4")
+	assert.Equal(t, 1, f.logEndChecks)
+}
+
+func TestLeaderEpochs_LogEndUnavailable(t *testing.T) {
+	f := &fakeEpochs{current: 4, logEndErr: sarama.ErrLeaderNotAvailable}
+	e := f.leaderEpochs()
+	err := e.check(newKafkaOffset("test-topic", 0, 100, 3))
+	assert.True(t, errors.Is(err, sarama.ErrLeaderNotAvailable))
+}
+
+type markSession struct {
+	sarama.ConsumerGroupSession
+	marked   []int64
+	metadata []string
+}
+
+func (s *markSession) MarkOffset(_ string, _ int32, offset int64, metadata string) {
+	s.marked = append(s.marked, offset)
+	s.metadata = append(s.metadata, metadata)
+}
+
+type ackRequest []sourcesdk.Offset
+
+func (r ackRequest) Offsets() []sourcesdk.Offset { return r }
+
+func TestAck_FencesTruncatedOffsets(t *testing.T) {
+	f := &fakeEpochs{current: 3, logEnd: 102}
+	sess := &markSession{}
+	k := &kafkaSource{
+		topic:   "test-topic",
+		handler: newConsumerHandler(1),
+		tracker: newOffsetTracker(),
+		epochs:  f.leaderEpochs(),
+		logger:  zap.NewNop(),
+	}
+	k.handler.sess = sess
+	offsets := make([]sourcesdk.Offset, 0, 3)
+	for o := int64(100); o < 103; o++ {
+		k.tracker.track("test-topic", 0, o)
+		offsets = append(offsets, GenerateSourceSdkOffset(&sarama.ConsumerMessage{Topic: "test-topic", Offset: o}, 3))
+	}
+
+	// offset 102 was truncated by the new leader
+	f.current = 4
+	k.Ack(context.Background(), ackRequest(offsets))
+	assert.Equal(t, []int64{100, 101}, sess.marked)
+	assert.Equal(t, []string{"leaderEpoch=4", "leaderEpoch=4"}, sess.metadata)
+}
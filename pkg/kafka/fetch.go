@@ -0,0 +1,108 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// fetchedRecord is a record returned by a fetch request.
+type fetchedRecord struct {
+	offset    int64
+	timestamp time.Time
+}
+
+// fetchRecords fetches the records of a partition from an offset with a single fetch request to its leader, without a
+// consumer. The records are returned as a consumer delivers them: the markers of transactions are left out, and so are
+// the records of aborted transactions when reading committed records only. partial tells that the fetch stopped at a
+// record too large for it, which is not returned.
+func fetchRecords(client sarama.Client, config *sarama.Config, topic string, partition int32, offset int64) ([]fetchedRecord, bool, error) {
+	broker, err := client.Leader(topic, partition)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get the leader of partition %d, %w", partition, err)
+	}
+	request := &sarama.FetchRequest{
+		Version:   fetchRequestVersion(config.Version),
+		MinBytes:  1,
+		MaxBytes:  config.Consumer.Fetch.Default,
+		Isolation: config.Consumer.IsolationLevel,
+	}
+	request.AddBlock(topic, partition, offset, config.Consumer.Fetch.Default, -1)
+	response, err := broker.Fetch(request)
+	if err != nil {
+		_ = broker.Close()
+		return nil, false, fmt.Errorf("failed to fetch partition %d from broker %d, %w", partition, broker.ID(), err)
+	}
+	block := response.GetBlock(topic, partition)
+	if block == nil {
+		return nil, false, fmt.Errorf("no records returned for partition %d, %w", partition, sarama.ErrIncompleteResponse)
+	}
+	if !errors.Is(block.Err, sarama.ErrNoError) {
+		return nil, false, fmt.Errorf("failed to fetch partition %d, %w", partition, block.Err)
+	}
+	aborted := make(map[int64]int64)
+	if config.Consumer.IsolationLevel == sarama.ReadCommitted {
+		for _, t := range block.AbortedTransactions {
+			aborted[t.ProducerID] = t.FirstOffset
+		}
+	}
+	var records []fetchedRecord
+	partial := false
+	for _, set := range block.RecordsSet {
+		if set.MsgSet != nil {
+			partial = partial || set.MsgSet.PartialTrailingMessage
+			for _, wrapper := range set.MsgSet.Messages {
+				messages := wrapper.Messages()
+				for _, m := range messages {
+					o, timestamp := m.Offset, m.Msg.Timestamp
+					if m.Msg.Version >= 1 {
+						// the offsets of the messages of a compressed wrapper are relative to the last one.
+						o += wrapper.Offset - messages[len(messages)-1].Offset
+						if m.Msg.LogAppendTime {
+							timestamp = wrapper.Msg.Timestamp
+						}
+					}
+					records = append(records, fetchedRecord{offset: o, timestamp: timestamp})
+				}
+			}
+		}
+		if batch := set.RecordBatch; batch != nil {
+			partial = partial || batch.PartialTrailingRecord
+			if batch.Control {
+				continue
+			}
+			if first, ok := aborted[batch.ProducerID]; ok && batch.IsTransactional && batch.FirstOffset >= first {
+				continue
+			}
+			for _, r := range batch.Records {
+				timestamp := batch.FirstTimestamp.Add(r.TimestampDelta)
+				if batch.LogAppendTime {
+					timestamp = batch.MaxTimestamp
+				}
+				records = append(records, fetchedRecord{offset: batch.FirstOffset + r.OffsetDelta, timestamp: timestamp})
+			}
+		}
+	}
+	// the response starts with the whole batch holding the offset.
+	for len(records) > 0 && records[0].offset < offset {
+		records = records[1:]
+	}
+	return records, partial, nil
+}
+
+// fetchRequestVersion returns the Fetch request version supported by the kafka version, the first one returning the
+// record batches of the transactions from 0.11.
+func fetchRequestVersion(version sarama.KafkaVersion) int16 {
+	switch {
+	case version.IsAtLeast(sarama.V0_11_0_0):
+		return 4
+	case version.IsAtLeast(sarama.V0_10_1_0):
+		return 3
+	case version.IsAtLeast(sarama.V0_10_0_0):
+		return 2
+	default:
+		return 0
+	}
+}
@@ -13,8 +13,6 @@ import (
  * This entire file is a copy of https://github.com/numaproj/numaflow/blob/main/pkg/sources/kafka/handler.go with small modifications
  */
 
-// TODO - Add unit tests for this file
-
 // consumerHandler struct
 type consumerHandler struct {
 	inflightacks chan bool
@@ -23,20 +21,31 @@ type consumerHandler struct {
 	messages     chan *sarama.ConsumerMessage
 	sess         sarama.ConsumerGroupSession
 	logger       *zap.SugaredLogger
+	// functions called with the claimed partitions of every new session
+	assignHooks []func(claims map[string][]int32)
+	// functions called at the end of every session
+	cleanupHooks []func()
 }
 
 // new handler initializes the channel for passing messages
 func newConsumerHandler(readChanSize int) *consumerHandler {
+	// there are no inflight acks until the first ack, so that a session can be cleaned up before.
+	inflightacks := make(chan bool)
+	close(inflightacks)
 	return &consumerHandler{
-		ready:    make(chan bool),
-		messages: make(chan *sarama.ConsumerMessage, readChanSize),
-		logger:   utils.NewLogger(),
+		inflightacks: inflightacks,
+		ready:        make(chan bool),
+		messages:     make(chan *sarama.ConsumerMessage, readChanSize),
+		logger:       utils.NewLogger(),
 	}
 }
 
 // Setup is run at the beginning of a new session, before ConsumeClaim
 func (consumer *consumerHandler) Setup(sess sarama.ConsumerGroupSession) error {
 	consumer.sess = sess
+	for _, hook := range consumer.assignHooks {
+		hook(sess.Claims())
+	}
 	consumer.readycloser.Do(func() {
 		close(consumer.ready)
 	})
@@ -45,6 +54,9 @@ func (consumer *consumerHandler) Setup(sess sarama.ConsumerGroupSession) error {
 
 // Cleanup is run at the end of a session, once all ConsumeClaim goroutines have exited
 func (consumer *consumerHandler) Cleanup(sess sarama.ConsumerGroupSession) error {
+	for _, hook := range consumer.cleanupHooks {
+		hook()
+	}
 	// wait for inflight acks to be completed.
 	<-consumer.inflightacks
 	sess.Commit()
@@ -61,7 +73,13 @@ func (consumer *consumerHandler) ConsumeClaim(session sarama.ConsumerGroupSessio
 			if !ok {
 				return nil
 			}
-			consumer.messages <- msg
+			// the message channel is full while the source doesn't read, during a drain or a reload.
+			select {
+			case consumer.messages <- msg:
+			case <-session.Context().Done():
+				consumer.logger.Info("context was canceled, stopping consumer claim")
+				return nil
+			}
 		case <-session.Context().Done():
 			consumer.logger.Info("context was canceled, stopping consumer claim")
 			return nil
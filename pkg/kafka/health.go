@@ -0,0 +1,132 @@
+package kafka
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+const (
+	defaultHealthErrorThreshold = 5
+	defaultHealthStallTimeout   = time.Minute
+)
+
+// consumerHealth tracks the state of the consumer the liveness and readiness of the source are derived from.
+type consumerHealth struct {
+	errorThreshold int
+	stallTimeout   time.Duration
+
+	lock sync.Mutex
+	// the consumer group session is active, with assigned partitions.
+	joined   bool
+	assigned int
+	// the consume loop is running, and the last time it was entered or left.
+	consuming bool
+	loopedAt  time.Time
+	// consecutive consumer errors without any record read in between.
+	errors  int
+	lastErr error
+	now     func() time.Time
+}
+
+func newConsumerHealth(c *config.Health) *consumerHealth {
+	h := &consumerHealth{
+		errorThreshold: defaultHealthErrorThreshold,
+		stallTimeout:   defaultHealthStallTimeout,
+		now:            time.Now,
+	}
+	if c != nil && c.ErrorThreshold > 0 {
+		h.errorThreshold = c.ErrorThreshold
+	}
+	if c != nil && c.StallTimeout > 0 {
+		h.stallTimeout = c.StallTimeout
+	}
+	h.loopedAt = h.now()
+	return h
+}
+
+// sessionStarted records that the consumer joined the group with the given number of assigned partitions.
+func (h *consumerHealth) sessionStarted(assigned int) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.joined = true
+	h.assigned = assigned
+	h.errors = 0
+}
+
+// sessionEnded records that the consumer group session is over, e.g. because of a rebalance.
+func (h *consumerHealth) sessionEnded() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.joined = false
+	h.assigned = 0
+}
+
+// consumeStarted records that the consume loop entered a new iteration.
+func (h *consumerHealth) consumeStarted() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.consuming = true
+	h.loopedAt = h.now()
+}
+
+// consumeReturned records that the consume loop left an iteration.
+func (h *consumerHealth) consumeReturned() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.consuming = false
+	h.loopedAt = h.now()
+}
+
+// recordError records a consumer error.
+func (h *consumerHealth) recordError(err error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.errors++
+	h.lastErr = err
+}
+
+// recordRead records that a record was read, which resets the consecutive errors.
+func (h *consumerHealth) recordRead() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.errors = 0
+}
+
+// live returns an error if the consumer errors keep repeating or if the consume loop has stopped for too long.
+func (h *consumerHealth) live() error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.errors >= h.errorThreshold {
+		return fmt.Errorf("%d consecutive consumer errors, last one: %w", h.errors, h.lastErr)
+	}
+	if stalled := h.now().Sub(h.loopedAt); !h.consuming && stalled > h.stallTimeout {
+		return fmt.Errorf("consume loop not running for %s", stalled.Round(time.Second))
+	}
+	return nil
+}
+
+// ready returns an error if the consumer has not joined the group or has no assigned partitions.
+func (h *consumerHealth) ready() error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if !h.joined {
+		return fmt.Errorf("consumer group not joined")
+	}
+	if h.assigned == 0 {
+		return fmt.Errorf("no partitions assigned")
+	}
+	return nil
+}
+
+// Live reports whether the source is consuming, it returns an error if the consumer is wedged.
+func (k *kafkaSource) Live() error {
+	return k.health.live()
+}
+
+// Ready reports whether the source is ready to read, i.e. it joined the consumer group and was assigned partitions.
+func (k *kafkaSource) Ready() error {
+	return k.health.ready()
+}
@@ -0,0 +1,56 @@
+package kafka
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+func TestConsumerHealth_Ready(t *testing.T) {
+	h := newConsumerHealth(nil)
+	assert.ErrorContains(t, h.ready(), "not joined")
+	h.sessionStarted(0)
+	assert.ErrorContains(t, h.ready(), "no partitions assigned")
+	h.sessionStarted(2)
+	assert.NoError(t, h.ready())
+	h.sessionEnded()
+	assert.ErrorContains(t, h.ready(), "not joined")
+}
+
+func TestConsumerHealth_LiveErrors(t *testing.T) {
+	h := newConsumerHealth(&config.Health{ErrorThreshold: 2})
+	h.consumeStarted()
+	assert.NoError(t, h.live())
+	h.recordError(errors.New("broker down"))
+	assert.NoError(t, h.live())
+	h.recordError(errors.New("broker down"))
+	assert.ErrorContains(t, h.live(), "2 consecutive consumer errors, last one: broker down")
+	// reading a record shows the consumer recovered
+	h.recordRead()
+	assert.NoError(t, h.live())
+	h.recordError(errors.New("broker down"))
+	h.recordError(errors.New("broker down"))
+	h.sessionStarted(1)
+	assert.NoError(t, h.live())
+}
+
+func TestConsumerHealth_LiveStall(t *testing.T) {
+	now := time.Now()
+	h := newConsumerHealth(&config.Health{StallTimeout: time.Minute})
+	h.now = func() time.Time { return now }
+	h.consumeStarted()
+	now = now.Add(time.Hour)
+	// a session can last for any time
+	assert.NoError(t, h.live())
+	h.consumeReturned()
+	now = now.Add(30 * time.Second)
+	assert.NoError(t, h.live())
+	now = now.Add(time.Minute)
+	assert.ErrorContains(t, h.live(), "consume loop not running for 1m30s")
+	h.consumeStarted()
+	assert.NoError(t, h.live())
+}
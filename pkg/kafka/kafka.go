@@ -2,15 +2,21 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/IBM/sarama"
 	sourcesdk "github.com/numaproj/numaflow-go/pkg/sourcer"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+	"github.com/numaproj-contrib/kafka-source-go/pkg/metrics"
 	"github.com/numaproj-contrib/kafka-source-go/pkg/utils"
 )
 
@@ -18,8 +24,6 @@ import (
  * This entire file is a re-implementation of https://github.com/numaproj/numaflow/blob/main/pkg/sources/kafka/reader.go
  */
 
-// TODO - Add unit tests for this file
-
 const pendingNotAvailable = int64(math.MinInt64)
 
 type kafkaSource struct {
@@ -38,9 +42,65 @@ type kafkaSource struct {
 	adminClient sarama.ClusterAdmin
 	// sarama client
 	saramaClient sarama.Client
+	// last pending messages computed in the background
+	pendingCache *pendingCache
+	// how often the pending messages are computed
+	pendingRefreshInterval time.Duration
+	// time lag config, nil if the time lag is not computed.
+	timeLag *config.TimeLag
 
 	volumeReader utils.VolumeReader
 
+	// snapshot mode config, nil if the source consumes the topic through the consumer group.
+	snapshot *config.Snapshot
+	// drops duplicated records, nil if deduplication is disabled.
+	dedup *deduplicator
+	// reassembles chunked messages, nil if chunking is disabled.
+	chunks *reassembler
+	// decodes CloudEvents, nil if the CloudEvents mode is disabled.
+	cloudEvents *cloudEventsDecoder
+	// splits batched records, nil if the splitting mode is disabled.
+	splitter *splitter
+	// packs records into aggregated messages, nil if the aggregation mode is disabled.
+	aggregator *aggregator
+	// messages left over from the previous read, emitted first by the next one.
+	backlog []sourcesdk.Message
+	// length of the backlog, for the pending messages computed while a read changes it
+	backlogSize atomic.Int64
+	// tracks the read offsets until they are safe to commit
+	tracker *offsetTracker
+	// fences the acks of the offsets truncated since they were read
+	epochs *leaderEpochs
+	// starts the consume spans of the records, nil if tracing is disabled.
+	tracer *recordTracer
+	// tracer provider the consume spans are started with, the global one by default.
+	tracerProvider trace.TracerProvider
+	// state of the consumer reported by the health probes
+	health *consumerHealth
+	// reconnection config, nil for the defaults.
+	retry *config.Retry
+	// how long a shutdown waits for the inflight acks
+	shutdownTimeout time.Duration
+	// set once the source is shutting down, no more messages are read.
+	draining atomic.Bool
+	// number of messages sent to the message channel and not acked yet
+	inflight atomic.Int64
+
+	// config the source runs with, replaced by the applied reloads
+	cfg *config.Config
+	// set while a reload restarts the consumer, no messages are read.
+	paused atomic.Bool
+	// serializes the reloads
+	reloading sync.Mutex
+	// held by the reads and the acks, and exclusively while a reload swaps the clients and the config
+	reloadLock sync.RWMutex
+	// guards stopWorkers and reload
+	workersLock sync.Mutex
+	// stops the consumer and the pending refresher of the run loop, nil if the run loop is not running
+	stopWorkers context.CancelFunc
+	// reload waiting for the run loop to apply it
+	reload *pendingReload
+
 	// context cancel function
 	cancelFn context.CancelFunc
 	// lifecycle context
@@ -48,6 +108,8 @@ type kafkaSource struct {
 
 	// channel to indicate that we are done
 	stopCh chan struct{}
+	// receives the error the source gave up with
+	errCh chan error
 
 	logger *zap.Logger
 }
@@ -70,12 +132,34 @@ func WithBufferSize(s int) Option {
 	}
 }
 
+// WithTracerProvider is used to set the tracer provider the consume spans are started with
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(k *kafkaSource) error {
+		k.tracerProvider = tp
+		return nil
+	}
+}
+
 func New(c *config.Config, opts ...Option) (*kafkaSource, error) {
 	k := &kafkaSource{
 		topic:           c.Topic,
 		brokers:         c.Brokers,
 		consumerGrpName: c.ConsumerGroupName,
+		snapshot:        c.Snapshot,
 		handlerBuffer:   100, // default buffer size for kafka reads
+		pendingCache:    &pendingCache{},
+		timeLag:         c.TimeLag,
+		health:          newConsumerHealth(c.Health),
+		retry:           c.Retry,
+		cfg:             c,
+	}
+	k.pendingRefreshInterval = c.PendingRefreshInterval
+	if k.pendingRefreshInterval <= 0 {
+		k.pendingRefreshInterval = defaultPendingRefreshInterval
+	}
+	k.shutdownTimeout = c.ShutdownTimeout
+	if k.shutdownTimeout <= 0 {
+		k.shutdownTimeout = defaultShutdownTimeout
 	}
 	for _, o := range opts {
 		if err := o(k); err != nil {
@@ -92,30 +176,11 @@ func New(c *config.Config, opts ...Option) (*kafkaSource, error) {
 	k.volumeReader = utils.NewKafkaVolumeReader(utils.SecretVolumePath)
 
 	sarama.NewConfig()
-	kConfig, err := configFromOpts(c.Config)
+	kConfig, err := NewSaramaConfig(c, k.volumeReader)
 	if err != nil {
-		return nil, fmt.Errorf("error reading kafka source config, %w", err)
-	}
-
-	if t := c.TLS; t != nil {
-		kConfig.Net.TLS.Enable = true
-		if c, err := utils.GetTLSConfig(t, k.volumeReader); err != nil {
-			return nil, err
-		} else {
-			kConfig.Net.TLS.Config = c
-		}
-	}
-	if s := c.SASL; s != nil {
-		if sasl, err := utils.GetSASL(s, k.volumeReader); err != nil {
-			return nil, err
-		} else {
-			kConfig.Net.SASL = *sasl
-		}
+		return nil, err
 	}
-
 	sarama.Logger = zap.NewStdLog(k.logger)
-	// return errors from the underlying kafka client using the Errors channel
-	kConfig.Consumer.Return.Errors = true
 	k.config = kConfig
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -123,21 +188,130 @@ func New(c *config.Config, opts ...Option) (*kafkaSource, error) {
 	k.lifecycleCtx = ctx
 
 	k.stopCh = make(chan struct{})
+	k.errCh = make(chan error, 1)
 	handler := newConsumerHandler(k.handlerBuffer)
 	k.handler = handler
 
+	k.tracker = newOffsetTracker()
+	k.epochs = newLeaderEpochs(k.currentLeaderEpoch, k.logEndOffset)
+	handler.assignHooks = append(handler.assignHooks, func(claims map[string][]int32) {
+		metrics.RebalanceTotal.WithLabelValues(k.topic).Inc()
+		k.health.sessionStarted(len(claims[k.topic]))
+		k.tracker.retain(k.topic, claims[k.topic])
+	})
+	handler.cleanupHooks = append(handler.cleanupHooks, k.health.sessionEnded)
+	if err := k.initStages(c); err != nil {
+		return nil, err
+	}
+	if k.chunks != nil {
+		handler.assignHooks = append(handler.assignHooks, func(claims map[string][]int32) {
+			k.chunks.retain(claims[k.topic])
+		})
+	}
+	if k.aggregator != nil {
+		handler.assignHooks = append(handler.assignHooks, func(claims map[string][]int32) {
+			k.aggregator.retain(claims[k.topic])
+		})
+	}
+	if c.Tracing != nil {
+		if k.tracerProvider == nil {
+			k.tracerProvider = otel.GetTracerProvider()
+		}
+		k.tracer = newRecordTracer(k.tracerProvider, k.topic, k.consumerGrpName, c.Tracing.Batch)
+	}
+	if k.dedup != nil {
+		handler.assignHooks = append(handler.assignHooks, func(claims map[string][]int32) {
+			k.dedup.retain(claims[k.topic])
+		})
+	}
+
+	if !c.SkipPreflight {
+		if err := k.preflight(); err != nil {
+			if !isUnavailable(err) {
+				return nil, err
+			}
+			// the connection is retried by the supervisor of the consumer.
+			k.logger.Warn("Preflight checks not completed, kafka is unavailable", zap.Error(err))
+		}
+	}
+
 	k.logger.Info("Starting Kafka consumer...")
 	go k.Start()
 	k.logger.Info("Kafka consumer started.")
 	return k, nil
 }
 
+// initStages creates the stages the records go through before being emitted, for the enabled modes.
+func (k *kafkaSource) initStages(c *config.Config) error {
+	var err error
+	if c.Chunking != nil {
+		k.chunks = newReassembler(c.Chunking)
+	}
+	if c.CloudEvents != nil {
+		k.cloudEvents = newCloudEventsDecoder(c.CloudEvents)
+	}
+	if c.Split != nil {
+		if k.splitter, err = newSplitter(c.Split); err != nil {
+			return fmt.Errorf("error reading kafka source split config, %w", err)
+		}
+	}
+	if c.Aggregate != nil {
+		if c.Split != nil {
+			return fmt.Errorf("kafka source split and aggregate modes can't be used together")
+		}
+		if k.aggregator, err = newAggregator(c.Aggregate); err != nil {
+			return fmt.Errorf("error reading kafka source aggregate config, %w", err)
+		}
+	}
+	if c.Dedup != nil {
+		if k.dedup, err = newDeduplicator(c.Dedup); err != nil {
+			return fmt.Errorf("error reading kafka source dedup config, %w", err)
+		}
+	}
+	return nil
+}
+
 func (k *kafkaSource) Start() {
+	sup := newSupervisor(k.retry, k.logger)
+	for {
+		err := k.connect()
+		if err == nil {
+			break
+		}
+		if rErr := sup.retry(k.lifecycleCtx, "connect to kafka", err); rErr != nil {
+			if k.lifecycleCtx.Err() != nil {
+				// the source was closed while connecting.
+				close(k.stopCh)
+				return
+			}
+			k.fail(fmt.Errorf("giving up connecting to kafka, %w", rErr))
+			close(k.stopCh)
+			return
+		}
+	}
+
+	if k.snapshot != nil {
+		// there is no consumer group session to wait for in snapshot mode.
+		k.handler.readycloser.Do(func() {
+			close(k.handler.ready)
+		})
+		go k.startSnapshot()
+	} else {
+		go k.run()
+	}
+	// wait for the consumer to setup.
+	select {
+	case <-k.handler.ready:
+		k.logger.Info("Consumer ready.")
+	case <-k.stopCh:
+	}
+}
+
+// connect creates the sarama client and the cluster admin client.
+func (k *kafkaSource) connect() error {
 	client, err := sarama.NewClient(k.brokers, k.config)
 	if err != nil {
-		k.logger.Panic("Failed to create sarama client", zap.Error(err))
-	} else {
-		k.saramaClient = client
+		return fmt.Errorf("failed to create sarama client, %w", err)
 	}
 	// Does it require any special privileges to create a cluster admin client?
 	adminClient, err := sarama.NewClusterAdminFromClient(client)
@@ -145,50 +319,37 @@ func (k *kafkaSource) Start() {
 		if !client.Closed() {
 			_ = client.Close()
 		}
-		k.logger.Panic("Failed to create sarama cluster admin client", zap.Error(err))
-	} else {
-		k.adminClient = adminClient
+		return fmt.Errorf("failed to create sarama cluster admin client, %w", err)
 	}
-
-	go k.startConsumer()
-	// wait for the consumer to setup.
-	<-k.handler.ready
-	k.logger.Info("Consumer ready.")
+	k.saramaClient = client
+	k.adminClient = adminClient
+	return nil
 }
 
 // Pending returns the number of pending records.
+// It is computed in the background, the last computed value is returned.
 func (k *kafkaSource) Pending(_ context.Context) int64 {
-	if k.adminClient == nil || k.saramaClient == nil {
+	defer func(start time.Time) {
+		metrics.PendingDuration.WithLabelValues(k.topic).Observe(time.Since(start).Seconds())
+	}(time.Now())
+	// the consumer group is not used in snapshot mode, so there are no committed offsets to compare with.
+	if k.snapshot != nil {
 		return pendingNotAvailable
 	}
-	partitions, err := k.saramaClient.Partitions(k.topic)
-	if err != nil {
-		return pendingNotAvailable
+	p, age, ok := k.cachedPending()
+	if ok || age > 0 {
+		metrics.PendingAge.WithLabelValues(k.topic).Set(age.Seconds())
 	}
-	totalPending := int64(0)
-	rep, err := k.adminClient.ListConsumerGroupOffsets(k.consumerGrpName, map[string][]int32{k.topic: partitions})
-	if err != nil {
-		err := k.refreshAdminClient()
-		if err != nil {
-			return pendingNotAvailable
+	if !ok {
+		if age > 0 {
+			k.logger.Warn("Pending messages not refreshed for too long, reporting them as not available", zap.Duration("age", age))
 		}
 		return pendingNotAvailable
 	}
-	for _, partition := range partitions {
-		block := rep.GetBlock(k.topic, partition)
-		if block.Offset == -1 {
-			// Note: if there is no offset associated with the partition under the consumer group, offset fetch sets the offset field to -1.
-			// This is not an error and usually means that there has been no data published to this particular partition yet.
-			// In this case, we can safely skip this partition from the pending calculation.
-			continue
-		}
-		partitionOffset, err := k.saramaClient.GetOffset(k.topic, partition, sarama.OffsetNewest)
-		if err != nil {
-			return pendingNotAvailable
-		}
-		totalPending += partitionOffset - block.Offset
-	}
-	return totalPending
+	metrics.PendingMessages.WithLabelValues(k.topic, metrics.LocationBuffered).Set(float64(p.buffered))
+	metrics.PendingMessages.WithLabelValues(k.topic, metrics.LocationBroker).Set(float64(p.broker))
+	k.logger.Debug("Pending messages", zap.Int64("total", p.total), zap.Int64("buffered", p.buffered), zap.Int64("broker", p.broker), zap.Duration("age", age))
+	return p.total
 }
 
 func (k *kafkaSource) Read(_ context.Context, readRequest sourcesdk.ReadRequest, messageCh chan<- sourcesdk.Message) {
@@ -196,24 +357,135 @@ func (k *kafkaSource) Read(_ context.Context, readRequest sourcesdk.ReadRequest,
 	ctx, cancel := context.WithTimeout(context.Background(), readRequest.TimeOut())
 	defer cancel()
 
+	if k.draining.Load() {
+		// the source is shutting down, the messages that are not read yet will be read again after the restart.
+		return
+	}
+	if k.paused.Load() {
+		// a reload is restarting the consumer.
+		return
+	}
+	k.reloadLock.RLock()
+	defer k.reloadLock.RUnlock()
+	metrics.BufferDepth.WithLabelValues(k.topic).Set(float64(len(k.handler.messages)))
+	k.dropExpiredChunks()
+	i := uint64(0)
+	for ; i < readRequest.Count() && len(k.backlog) > 0; i++ {
+		k.inflight.Add(1)
+		messageCh <- k.backlog[0]
+		k.backlog = k.backlog[1:]
+	}
+	defer func() {
+		k.backlogSize.Store(int64(len(k.backlog)))
+	}()
+	// observe starts tracing a record read from the topic, the returned function is called once it is processed.
+	observe := func(*sarama.ConsumerMessage) func() { return func() {} }
+	if k.tracer != nil && !k.tracer.batch {
+		observe = func(m *sarama.ConsumerMessage) func() {
+			span := k.tracer.startRecordSpan(m)
+			return func() { span.End() }
+		}
+	} else if k.tracer != nil {
+		// the batch span is recorded once the read is over, linked to the records read.
+		start := time.Now()
+		var links []trace.Link
+		read := 0
+		defer func() {
+			k.tracer.recordBatchSpan(start, read, links)
+		}()
+		observe = func(m *sarama.ConsumerMessage) func() {
+			read++
+			if link, ok := k.tracer.recordLink(m); ok {
+				links = append(links, link)
+			}
+			return func() {}
+		}
+	}
+	// emit sends a message to the message channel, or keeps it for the next read once the count is reached.
+	emit := func(msgs []sourcesdk.Message) {
+		for _, msg := range msgs {
+			if i < readRequest.Count() {
+				k.inflight.Add(1)
+				messageCh <- msg
+				i++
+			} else {
+				k.backlog = append(k.backlog, msg)
+			}
+		}
+	}
 	// Read the data from the source and send the data to the message channel.
-	for i := 0; uint64(i) < readRequest.Count(); i++ {
+	for i < readRequest.Count() {
 		select {
 		case <-ctx.Done():
 			// If the context is done, the read request is timed out.
+			// Partially filled aggregated messages are emitted, so that they don't wait for more records forever.
+			emit(k.flushAggregates())
 			return
 		case m := <-k.handler.messages:
+			metrics.ReadTotal.WithLabelValues(k.topic).Inc()
+			metrics.ReadBytesTotal.WithLabelValues(k.topic).Add(float64(len(m.Value)))
+			k.health.recordRead()
+			k.tracker.track(m.Topic, m.Partition, m.Offset)
+			done := observe(m)
+			m, ok := k.prepare(m)
+			if !ok {
+				done()
+				continue
+			}
 			// Otherwise, we read the data from the source and send the data to the message channel.
-			messageCh <- toSDKMessage(m)
+			emit(k.toSDKMessages(m))
+			done()
+		}
+	}
+}
+
+// prepare runs a record read from the topic through the enabled stages. It returns false if the record is not to be
+// emitted, either because it is buffered or because it was acked right away.
+func (k *kafkaSource) prepare(m *sarama.ConsumerMessage) (*sarama.ConsumerMessage, bool) {
+	if k.chunks != nil {
+		assembled, members, err := k.chunks.add(m)
+		if err != nil {
+			metrics.DecodeErrorsTotal.WithLabelValues(k.topic, "chunking").Inc()
+			k.logger.Error("Invalid chunk, emitting it as is", zap.Int32("partition", m.Partition), zap.Int64("offset", m.Offset), zap.Error(err))
+		}
+		if assembled == nil {
+			k.dropExpiredChunks()
+			return nil, false
+		}
+		k.tracker.bind(m.Topic, m.Partition, assembled.Offset, members)
+		m = assembled
+	}
+	if k.dedup != nil && k.dedup.isDuplicate(m) {
+		// duplicates are acked right away without being emitted.
+		k.ackOffset(m.Topic, m.Partition, m.Offset)
+		return nil, false
+	}
+	return m, true
+}
+
+// dropExpiredChunks acks the chunks of the incomplete messages that timed out or no longer fit in the buffer,
+// so that they don't hold back the commits of their partitions.
+func (k *kafkaSource) dropExpiredChunks() {
+	if k.chunks == nil {
+		return
+	}
+	for _, cm := range k.chunks.expire() {
+		k.logger.Warn("Dropping incomplete chunked message", zap.Int32("partition", cm.key.partition), zap.String("messageId", cm.key.id), zap.Int("received", cm.received), zap.Int("total", len(cm.chunks)))
+		for _, o := range cm.offsets {
+			k.ackOffset(cm.topic, cm.key.partition, o)
 		}
 	}
 }
 
 // Ack acknowledges the data from the source.
 func (k *kafkaSource) Ack(_ context.Context, request sourcesdk.AckRequest) {
+	k.reloadLock.RLock()
+	defer k.reloadLock.RUnlock()
 	// we want to block the handler from exiting if there are any inflight acks.
 	k.handler.inflightacks = make(chan bool)
 	defer close(k.handler.inflightacks)
+	metrics.AckTotal.WithLabelValues(k.topic).Add(float64(len(request.Offsets())))
+	defer k.inflight.Add(-int64(len(request.Offsets())))
 
 	for _, offset := range request.Offsets() {
 		kOffset, err := ToKafkaOffset(&offset)
@@ -221,6 +493,11 @@ func (k *kafkaSource) Ack(_ context.Context, request sourcesdk.AckRequest) {
 			k.logger.Panic("Unable to convert offset to kafka offset", zap.Error(err))
 		}
 		topic := kOffset.Topic()
+		if topic != k.topic {
+			// read before a reload switched topics, its partition and offset don't address the records of this topic.
+			k.logger.Warn("Dropping the ack of an offset of another topic", zap.String("supplied-offset", kOffset.String()), zap.String("topic", k.topic))
+			continue
+		}
 
 		// we need to mark the offset of the next message to read
 		pOffset, err := kOffset.Sequence()
@@ -228,7 +505,56 @@ func (k *kafkaSource) Ack(_ context.Context, request sourcesdk.AckRequest) {
 			k.logger.Error("Unable to extract partition offset of type int64 from the supplied offset. skipping and continuing", zap.String("supplied-offset", kOffset.String()), zap.Error(err))
 			continue
 		}
-		k.handler.sess.MarkOffset(topic, kOffset.PartitionIdx(), pOffset, "")
+		if err := k.epochs.check(kOffset); err != nil {
+			// the offset stays unacked, so that no later offset of the partition is committed past it either.
+			k.logger.Error("Not committing an offset that may no longer exist after a leader change", zap.String("supplied-offset", kOffset.String()), zap.Error(err))
+			continue
+		}
+		if subIndex, ok := kOffset.SubIndex(); ok {
+			k.ackSplitOffset(topic, kOffset.PartitionIdx(), pOffset, subIndex)
+		} else {
+			k.ackOffset(topic, kOffset.PartitionIdx(), pOffset)
+		}
+	}
+}
+
+// ackOffset acks an offset read from the partition, and marks the highest offset of the partition that became safe
+// to commit.
+func (k *kafkaSource) ackOffset(topic string, partition int32, offset int64) {
+	if committable, ok := k.tracker.ack(topic, partition, offset); ok {
+		k.markOffset(topic, partition, committable)
+	}
+}
+
+// ackSplitOffset acks one of the messages a record was split into, and marks the highest offset of the partition that
+// became safe to commit.
+func (k *kafkaSource) ackSplitOffset(topic string, partition int32, offset int64, subIndex int32) {
+	if committable, ok := k.tracker.ackChild(topic, partition, offset, subIndex); ok {
+		k.markOffset(topic, partition, committable)
+	}
+}
+
+// markOffset marks the offset of a partition as consumed in the current consumer group session.
+func (k *kafkaSource) markOffset(topic string, partition int32, offset int64) {
+	// offsets are not committed in snapshot mode, every restart rebuilds the snapshot.
+	if k.snapshot != nil {
+		return
+	}
+	k.handler.sess.MarkOffset(topic, partition, offset, k.epochs.metadata(topic, partition))
+}
+
+// Err returns a channel receiving the error the source gave up with, once the retries of the kafka failures are
+// exhausted or on a failure retrying can't fix. The source doesn't read any more messages then, and has to be shut down.
+func (k *kafkaSource) Err() <-chan error {
+	return k.errCh
+}
+
+// fail reports the error the source gave up with.
+func (k *kafkaSource) fail(err error) {
+	k.logger.Error("Kafka source failed", zap.Error(err))
+	select {
+	case k.errCh <- err:
+	default:
 	}
 }
 
@@ -236,17 +562,49 @@ func (k *kafkaSource) Close() error {
 	k.logger.Info("Closing kafka reader...")
 	// finally, shut down the client
 	k.cancelFn()
+	// a reload may be swapping the clients.
+	k.reloadLock.Lock()
 	if k.adminClient != nil {
 		// closes the underlying sarama client as well.
 		if err := k.adminClient.Close(); err != nil {
 			k.logger.Error("Error in closing kafka admin client", zap.Error(err))
 		}
 	}
+	k.reloadLock.Unlock()
 	<-k.stopCh
 	k.logger.Info("Kafka reader closed")
 	return nil
 }
 
+// NewSaramaConfig builds the sarama config of the source, with the TLS and SASL settings read from the secrets mounted
+// where reader reads them.
+func NewSaramaConfig(c *config.Config, reader utils.VolumeReader) (*sarama.Config, error) {
+	kConfig, err := configFromOpts(c.Config)
+	if err != nil {
+		return nil, fmt.Errorf("error reading kafka source config, %w", err)
+	}
+
+	if t := c.TLS; t != nil {
+		kConfig.Net.TLS.Enable = true
+		if c, err := utils.GetTLSConfig(t, reader); err != nil {
+			return nil, err
+		} else {
+			kConfig.Net.TLS.Config = c
+		}
+	}
+	if s := c.SASL; s != nil {
+		if sasl, err := utils.GetSASL(s, reader); err != nil {
+			return nil, err
+		} else {
+			kConfig.Net.SASL = *sasl
+		}
+	}
+
+	// return errors from the underlying kafka client using the Errors channel
+	kConfig.Consumer.Return.Errors = true
+	return kConfig, nil
+}
+
 func configFromOpts(yamlConfig string) (*sarama.Config, error) {
 	config, err := utils.GetSaramaConfigFromYAMLString(yamlConfig)
 	if err != nil {
@@ -256,68 +614,163 @@ func configFromOpts(yamlConfig string) (*sarama.Config, error) {
 	return config, nil
 }
 
-func (k *kafkaSource) startConsumer() {
+// startConsumer consumes the topic until ctx is done, when the source is closed or reloaded. When consuming fails, the consumer group is rebuilt
+// after a backoff, and the source gives up once the retries are exhausted or the error is fatal.
+func (k *kafkaSource) startConsumer(ctx context.Context) {
+	sup := newSupervisor(k.retry, k.logger)
+	for ctx.Err() == nil {
+		err := k.consume(ctx, sup)
+		if err == nil || ctx.Err() != nil {
+			continue
+		}
+		metrics.ConsumerErrorsTotal.WithLabelValues(k.topic).Inc()
+		k.health.recordError(err)
+		if rErr := sup.retry(ctx, "consume from kafka", err); rErr != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			k.fail(fmt.Errorf("giving up consuming from kafka, %w", rErr))
+			return
+		}
+	}
+}
+
+// consume creates a consumer group and consumes the topic until ctx is done or consuming fails. The consumer
+// group is closed on return, which leaves the group, so that the next attempt rebuilds it.
+func (k *kafkaSource) consume(ctx context.Context, sup *supervisor) error {
 	client, err := sarama.NewConsumerGroup(k.brokers, k.consumerGrpName, k.config)
 	k.logger.Info("creating NewConsumerGroup", zap.String("topic", k.topic), zap.String("consumerGroupName", k.consumerGrpName), zap.Strings("brokers", k.brokers))
 	if err != nil {
-		k.logger.Panic("Problem initializing sarama client", zap.Error(err))
+		return fmt.Errorf("failed to create consumer group, %w", err)
 	}
 	wg := new(sync.WaitGroup)
+	defer func() {
+		_ = client.Close()
+		wg.Wait()
+	}()
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		for {
-			select {
-			case <-k.lifecycleCtx.Done():
-				return
-			case cErr := <-client.Errors():
-				k.logger.Error("Kafka consumer error", zap.Error(cErr))
-			}
+		// the errors channel is closed when the consumer group is closed.
+		for cErr := range client.Errors() {
+			metrics.ConsumerErrorsTotal.WithLabelValues(k.topic).Inc()
+			k.health.recordError(cErr)
+			k.logger.Error("Kafka consumer error", zap.Error(cErr))
 		}
 	}()
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		for {
-			// `Consume` should be called inside an infinite loop; when a
-			// server-side re-balance happens, the consumer session will need to be
-			// recreated to get the new claims
-			if conErr := client.Consume(k.lifecycleCtx, []string{k.topic}, k.handler); conErr != nil {
-				// Panic on errors to let it crash and restart the process
-				k.logger.Panic("Kafka consumer failed with error: ", zap.Error(conErr))
-			}
-			// check if context was cancelled, signaling that the consumer should stop
-			if k.lifecycleCtx.Err() != nil {
-				return
-			}
+	for {
+		// `Consume` should be called inside an infinite loop; when a
+		// server-side re-balance happens, the consumer session will need to be
+		// recreated to get the new claims
+		k.health.consumeStarted()
+		started := time.Now()
+		conErr := client.Consume(ctx, []string{k.topic}, k.handler)
+		k.health.consumeReturned()
+		// check if context was cancelled, signaling that the consumer should stop
+		if ctx.Err() != nil {
+			return nil
 		}
-	}()
-	wg.Wait()
-	_ = client.Close()
-	close(k.stopCh)
+		if conErr != nil {
+			sup.failedAfter(time.Since(started))
+			return fmt.Errorf("kafka consumer failed, %w", conErr)
+		}
+		sup.succeeded()
+	}
 }
 
-// refreshAdminClient refreshes the admin client
-func (k *kafkaSource) refreshAdminClient() error {
-	if _, err := k.saramaClient.RefreshController(); err != nil {
-		return fmt.Errorf("failed to refresh controller, %w", err)
+// toSDKMessages converts a record to SDK messages, one per event if the record is split. In aggregation mode, the
+// record is added to the batch of its partition and the batches that are full are returned.
+func (k *kafkaSource) toSDKMessages(m *sarama.ConsumerMessage) []sourcesdk.Message {
+	epoch := k.leaderEpoch(m.Topic, m.Partition)
+	if k.aggregator != nil {
+		msg := k.toSDKMessage(m, GenerateSourceSdkOffset(m, epoch))
+		return k.toAggregatedMessages(k.aggregator.add(m.Topic, m.Partition, m.Offset, msg.Value(), msg.EventTime()))
+	}
+	if k.splitter == nil {
+		return []sourcesdk.Message{k.toSDKMessage(m, GenerateSourceSdkOffset(m, epoch))}
 	}
-	// we are not closing the old admin client because it will close the underlying sarama client as well
-	// it is safe to not close the admin client,
-	// since we are using the same sarama client, we will not leak any resources(tcp connections)
-	admin, err := sarama.NewClusterAdminFromClient(k.saramaClient)
+	values, err := k.splitter.split(m.Value)
 	if err != nil {
-		return fmt.Errorf("failed to create new admin client, %w", err)
+		metrics.DecodeErrorsTotal.WithLabelValues(k.topic, "split").Inc()
+		k.logger.Error("Failed to split record, emitting it as is", zap.Int32("partition", m.Partition), zap.Int64("offset", m.Offset), zap.Error(err))
+		return []sourcesdk.Message{k.toSDKMessage(m, GenerateSourceSdkOffset(m, epoch))}
 	}
-	k.adminClient = admin
-	return nil
+	if len(values) == 0 {
+		// nothing to emit, the record is acked right away.
+		k.ackOffset(m.Topic, m.Partition, m.Offset)
+		return nil
+	}
+	k.tracker.expand(m.Topic, m.Partition, m.Offset, len(values))
+	msgs := make([]sourcesdk.Message, 0, len(values))
+	for idx, v := range values {
+		child := *m
+		child.Value = v
+		msgs = append(msgs, k.toSDKMessage(&child, GenerateSplitSourceSdkOffset(m, epoch, int32(idx))))
+	}
+	return msgs
+}
+
+// flushAggregates returns the partially filled aggregated messages.
+func (k *kafkaSource) flushAggregates() []sourcesdk.Message {
+	if k.aggregator == nil {
+		return nil
+	}
+	return k.toAggregatedMessages(k.aggregator.flush())
+}
+
+// toAggregatedMessages packs every batch into one SDK message, with the highest offset of the batch. Acking the message
+// acks all the records of the batch.
+func (k *kafkaSource) toAggregatedMessages(batches []*batch) []sourcesdk.Message {
+	msgs := make([]sourcesdk.Message, 0, len(batches))
+	for _, b := range batches {
+		highest, others := b.highestOffset()
+		value, err := k.aggregator.encode(b.values)
+		if err != nil {
+			// the records are acked so that they don't hold back the commits of the partition.
+			metrics.DecodeErrorsTotal.WithLabelValues(k.topic, "aggregate").Inc()
+			k.logger.Error("Failed to encode aggregated message, dropping it", zap.Int32("partition", b.partition), zap.Int64s("offsets", b.offsets), zap.Error(err))
+			for _, o := range b.offsets {
+				k.ackOffset(b.topic, b.partition, o)
+			}
+			continue
+		}
+		k.tracker.bind(b.topic, b.partition, highest, others)
+		offset := newKafkaOffset(b.topic, b.partition, highest, k.leaderEpoch(b.topic, b.partition)).ToSourceOffset()
+		msgs = append(msgs, sourcesdk.NewMessage(value, offset, b.eventTime))
+	}
+	return msgs
+}
+
+// toSDKMessage converts a record to an SDK message with the given offset, using the configured decoder if any.
+func (k *kafkaSource) toSDKMessage(m *sarama.ConsumerMessage, offset sourcesdk.Offset) sourcesdk.Message {
+	if k.cloudEvents != nil {
+		msg, err := k.cloudEvents.toSDKMessage(m, offset)
+		if err == nil {
+			return msg
+		}
+		if !errors.Is(err, errNotCloudEvent) {
+			metrics.DecodeErrorsTotal.WithLabelValues(k.topic, "cloudEvents").Inc()
+			k.logger.Error("Failed to decode cloud event, emitting the record as is", zap.Int32("partition", m.Partition), zap.Int64("offset", m.Offset), zap.Error(err))
+		}
+	}
+	return toSDKMessage(m, offset)
 }
 
-func toSDKMessage(m *sarama.ConsumerMessage) sourcesdk.Message {
+func toSDKMessage(m *sarama.ConsumerMessage, offset sourcesdk.Offset) sourcesdk.Message {
 	return sourcesdk.NewMessage(
 		m.Value,
-		GenerateSourceSdkOffset(m),
+		offset,
 		m.Timestamp)
 }
+
+// header returns the value of the first header of m with the given name.
+func header(m *sarama.ConsumerMessage, name string) ([]byte, bool) {
+	for _, h := range m.Headers {
+		if h != nil && string(h.Key) == name {
+			return h.Value, true
+		}
+	}
+	return nil, false
+}
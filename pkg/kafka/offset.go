@@ -1,6 +1,7 @@
 package kafka
 
 import (
+	"encoding/binary"
 	"fmt"
 	"strconv"
 	"strings"
@@ -10,22 +11,56 @@ import (
 )
 
 // To translate a kafka offset to a source offset, we need to store the topic name, partition index and offset value
-// in the source offset value. We use the following format:
+// in the source offset value. The value is encoded in a versioned binary format:
+// <version:1 byte> <fields:uvarint> <topic length:uvarint> <topic> <partition:varint> <offset:varint> [optional fields]
+// The fields bit set tells which of the optional fields follow, in the order of their bits: the sub index of a message
+// within a split record and the leader epoch of the record. New optional fields can be added with new bits, a change of
+// the layout needs a new version.
+//
+// Offsets used to be encoded in a text format, which is still parsed:
 // <topic>*<offset>
 // For example, if the topic name is "test-topic", the partition index is 0 and the offset value is 123, the source offset value will be:
 // test-topic*123
+// When a record is split into several messages, the index of the message within the record is appended:
+// <topic>*<offset>*<sub-index>
+// For example, the third message of the record above has the source offset value:
+// test-topic*123*2
 // We use "*" as the separator because it is not allowed in a topic name.
 // The topic name can be up to 255 characters in length, and can include the following characters: a-z, A-Z, 0-9, . (dot), _ (underscore), and - (dash).
+// The version byte of the binary format is not a valid topic character, so that both formats can't be confused.
 const offsetSeparator = "*"
 
+const (
+	// offsetEncodingV1 is the version of the binary offset encoding.
+	offsetEncodingV1 byte = 1
+
+	offsetHasSubIndex    uint64 = 1 << 0
+	offsetHasLeaderEpoch uint64 = 1 << 1
+	// offsetKnownFields are the optional fields version 1 can carry.
+	offsetKnownFields = offsetHasSubIndex | offsetHasLeaderEpoch
+)
+
 type KafkaOffset struct {
 	offset       int64
 	partitionIdx int32
 	topic        string
+	// index of the message within the record, only set if the record was split into several messages
+	subIndex    int32
+	hasSubIndex bool
+	// leader epoch of the record, only set if the broker reported it
+	leaderEpoch    int32
+	hasLeaderEpoch bool
 }
 
+// String returns the offset as <topic>:<partition>:<offset>, followed by :<sub-index> for a split record, as it is
+// logged. Older versions logged it as <topic>:<offset>:<partition>, and encoded the offset values as <topic>*<offset>.
+// Only the logs changed, the encoded values are not derived from it.
 func (k *KafkaOffset) String() string {
-	return fmt.Sprintf("%s:%d:%d", k.topic, k.offset, k.partitionIdx)
+	s := fmt.Sprintf("%s:%d:%d", k.topic, k.partitionIdx, k.offset)
+	if k.hasSubIndex {
+		s = fmt.Sprintf("%s:%d", s, k.subIndex)
+	}
+	return s
 }
 
 func (k *KafkaOffset) Sequence() (int64, error) {
@@ -40,20 +75,61 @@ func (k *KafkaOffset) Topic() string {
 	return k.topic
 }
 
-// GenerateSourceSdkOffset generates a source offset from a kafka message
-func GenerateSourceSdkOffset(m *sarama.ConsumerMessage) sourcesdk.Offset {
+// SubIndex returns the index of the message within the record, and false if the record wasn't split.
+func (k *KafkaOffset) SubIndex() (int32, bool) {
+	return k.subIndex, k.hasSubIndex
+}
+
+// LeaderEpoch returns the leader epoch of the record, and false if it is unknown.
+func (k *KafkaOffset) LeaderEpoch() (int32, bool) {
+	return k.leaderEpoch, k.hasLeaderEpoch
+}
+
+// GenerateSourceSdkOffset generates a source offset from a kafka message read under the given leader epoch, which is
+// left out of the offset if it is unknown (negative).
+func GenerateSourceSdkOffset(m *sarama.ConsumerMessage, leaderEpoch int32) sourcesdk.Offset {
+	return newKafkaOffset(m.Topic, m.Partition, m.Offset, leaderEpoch).ToSourceOffset()
+}
+
+// GenerateSplitSourceSdkOffset generates the source offset of one of the messages a kafka message was split into
+func GenerateSplitSourceSdkOffset(m *sarama.ConsumerMessage, leaderEpoch int32, subIndex int32) sourcesdk.Offset {
+	k := newKafkaOffset(m.Topic, m.Partition, m.Offset, leaderEpoch)
+	k.subIndex, k.hasSubIndex = subIndex, true
+	return k.ToSourceOffset()
+}
+
+func newKafkaOffset(topic string, partition int32, offset int64, leaderEpoch int32) *KafkaOffset {
 	k := &KafkaOffset{
-		offset:       m.Offset,
-		partitionIdx: m.Partition,
-		topic:        m.Topic,
+		offset:       offset,
+		partitionIdx: partition,
+		topic:        topic,
 	}
-	return k.ToSourceOffset()
+	if leaderEpoch >= 0 {
+		k.leaderEpoch, k.hasLeaderEpoch = leaderEpoch, true
+	}
+	return k
 }
 
 func ToKafkaOffset(o *sourcesdk.Offset) (*KafkaOffset, error) {
+	value := o.Value()
+	if len(value) > 0 && value[0] == offsetEncodingV1 {
+		k, err := decodeOffset(value[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid offset value %q, %w", value, err)
+		}
+		if o.PartitionId() != strconv.Itoa(int(k.partitionIdx)) {
+			return nil, fmt.Errorf("invalid partition id %s, the offset value is of partition %d", o.PartitionId(), k.partitionIdx)
+		}
+		return k, nil
+	}
+	return parseLegacyOffset(o)
+}
+
+// parseLegacyOffset parses an offset in the text format.
+func parseLegacyOffset(o *sourcesdk.Offset) (*KafkaOffset, error) {
 	strVal := string(o.Value())
 	strs := strings.Split(strVal, offsetSeparator)
-	if len(strs) != 2 {
+	if len(strs) != 2 && len(strs) != 3 {
 		return nil, fmt.Errorf("invalid offset value %s, the value cannot be divided to topic and offset", strVal)
 	}
 	var offset int
@@ -65,14 +141,118 @@ func ToKafkaOffset(o *sourcesdk.Offset) (*KafkaOffset, error) {
 	if partitionIdx, err = strconv.Atoi(o.PartitionId()); err != nil {
 		return nil, fmt.Errorf("invalid partition id %s", o.PartitionId())
 	}
-	return &KafkaOffset{
+	k := &KafkaOffset{
 		topic:        strs[0],
 		offset:       int64(offset),
 		partitionIdx: int32(partitionIdx),
-	}, nil
+	}
+	if len(strs) == 3 {
+		subIndex, err := strconv.ParseInt(strs[2], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sub index %s", strs[2])
+		}
+		k.subIndex, k.hasSubIndex = int32(subIndex), true
+	}
+	return k, nil
 }
 
 func (k *KafkaOffset) ToSourceOffset() sourcesdk.Offset {
-	value := []byte(fmt.Sprintf("%s%s%d", k.topic, offsetSeparator, k.offset))
-	return sourcesdk.NewOffset(value, strconv.Itoa(int(k.partitionIdx)))
+	return sourcesdk.NewOffset(k.encode(), strconv.Itoa(int(k.partitionIdx)))
+}
+
+// encode encodes the offset in the binary format.
+func (k *KafkaOffset) encode() []byte {
+	var fields uint64
+	if k.hasSubIndex {
+		fields |= offsetHasSubIndex
+	}
+	if k.hasLeaderEpoch {
+		fields |= offsetHasLeaderEpoch
+	}
+	b := make([]byte, 0, 16+len(k.topic))
+	b = append(b, offsetEncodingV1)
+	b = binary.AppendUvarint(b, fields)
+	b = binary.AppendUvarint(b, uint64(len(k.topic)))
+	b = append(b, k.topic...)
+	b = binary.AppendVarint(b, int64(k.partitionIdx))
+	b = binary.AppendVarint(b, k.offset)
+	if k.hasSubIndex {
+		b = binary.AppendVarint(b, int64(k.subIndex))
+	}
+	if k.hasLeaderEpoch {
+		b = binary.AppendVarint(b, int64(k.leaderEpoch))
+	}
+	return b
+}
+
+// decodeOffset decodes an offset in version 1 of the binary format, without the version byte.
+func decodeOffset(b []byte) (*KafkaOffset, error) {
+	d := offsetDecoder{b: b}
+	fields := d.uvarint("fields")
+	if d.err == nil && fields&^offsetKnownFields != 0 {
+		return nil, fmt.Errorf("unknown fields %#x", fields&^offsetKnownFields)
+	}
+	topicLen := d.uvarint("topic length")
+	if d.err == nil && topicLen > uint64(len(d.b)) {
+		return nil, fmt.Errorf("topic length %d exceeds the %d remaining bytes", topicLen, len(d.b))
+	}
+	k := &KafkaOffset{}
+	if d.err == nil {
+		k.topic, d.b = string(d.b[:topicLen]), d.b[topicLen:]
+	}
+	k.partitionIdx = d.varint32("partition")
+	k.offset = d.varint("offset")
+	if fields&offsetHasSubIndex != 0 {
+		k.subIndex, k.hasSubIndex = d.varint32("sub index"), true
+	}
+	if fields&offsetHasLeaderEpoch != 0 {
+		k.leaderEpoch, k.hasLeaderEpoch = d.varint32("leader epoch"), true
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	if len(d.b) > 0 {
+		return nil, fmt.Errorf("%d trailing bytes", len(d.b))
+	}
+	return k, nil
+}
+
+// offsetDecoder reads the varints of an encoded offset, the first error is kept and the next reads return zero values.
+type offsetDecoder struct {
+	b   []byte
+	err error
+}
+
+func (d *offsetDecoder) uvarint(name string) uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.err = fmt.Errorf("invalid %s", name)
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *offsetDecoder) varint(name string) int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.err = fmt.Errorf("invalid %s", name)
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *offsetDecoder) varint32(name string) int32 {
+	v := d.varint(name)
+	if d.err == nil && int64(int32(v)) != v {
+		d.err = fmt.Errorf("%s %d out of range", name, v)
+	}
+	return int32(v)
 }
@@ -9,11 +9,17 @@ import (
 )
 
 func TestSdkAndKafkaTransformation(t *testing.T) {
+	// offsets in the legacy text format are still parsed, and are converted back in the binary format
 	originalSrcOffset := sourcesdk.NewOffset([]byte("test-topic*100"), "0")
 	kafkaOffset, err := ToKafkaOffset(&originalSrcOffset)
 	assert.NoError(t, err)
+	assert.Equal(t, &KafkaOffset{offset: 100, partitionIdx: 0, topic: "test-topic"}, kafkaOffset)
 	convertBackToSrc := kafkaOffset.ToSourceOffset()
-	assert.Equal(t, originalSrcOffset, convertBackToSrc)
+	assert.Equal(t, offsetEncodingV1, convertBackToSrc.Value()[0])
+	assert.Equal(t, originalSrcOffset.PartitionId(), convertBackToSrc.PartitionId())
+	convertBackToKafka, err := ToKafkaOffset(&convertBackToSrc)
+	assert.NoError(t, err)
+	assert.Equal(t, kafkaOffset, convertBackToKafka)
 
 	originalKafkaOffset := &KafkaOffset{
 		offset:       100,
@@ -21,7 +27,7 @@ func TestSdkAndKafkaTransformation(t *testing.T) {
 		topic:        "test-topic",
 	}
 	srcOffset := originalKafkaOffset.ToSourceOffset()
-	convertBackToKafka, err := ToKafkaOffset(&srcOffset)
+	convertBackToKafka, err = ToKafkaOffset(&srcOffset)
 	assert.NoError(t, err)
 	assert.Equal(t, originalKafkaOffset, convertBackToKafka)
 }
@@ -32,10 +38,138 @@ func TestGenerateSourceSdkOffset(t *testing.T) {
 		Partition: 0,
 		Topic:     "test-topic",
 	}
-	generatedOffset := GenerateSourceSdkOffset(m)
+	generatedOffset := GenerateSourceSdkOffset(m, 5)
 	kafkaOffset, err := ToKafkaOffset(&generatedOffset)
 	assert.NoError(t, err)
 	assert.Equal(t, int64(100), kafkaOffset.offset)
 	assert.Equal(t, int32(0), kafkaOffset.partitionIdx)
 	assert.Equal(t, "test-topic", kafkaOffset.topic)
+	epoch, ok := kafkaOffset.LeaderEpoch()
+	assert.True(t, ok)
+	assert.Equal(t, int32(5), epoch)
+
+	// the leader epoch is left out when the brokers don't report it
+	generatedOffset = GenerateSourceSdkOffset(m, unknownLeaderEpoch)
+	kafkaOffset, err = ToKafkaOffset(&generatedOffset)
+	assert.NoError(t, err)
+	_, ok = kafkaOffset.LeaderEpoch()
+	assert.False(t, ok)
+}
+
+func TestSplitOffsetTransformation(t *testing.T) {
+	m := &sarama.ConsumerMessage{
+		Offset:    100,
+		Partition: 1,
+		Topic:     "test-topic",
+	}
+	generatedOffset := GenerateSplitSourceSdkOffset(m, unknownLeaderEpoch, 2)
+	kafkaOffset, err := ToKafkaOffset(&generatedOffset)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), kafkaOffset.offset)
+	assert.Equal(t, int32(1), kafkaOffset.partitionIdx)
+	subIndex, ok := kafkaOffset.SubIndex()
+	assert.True(t, ok)
+	assert.Equal(t, int32(2), subIndex)
+	assert.Equal(t, generatedOffset, kafkaOffset.ToSourceOffset())
+
+	legacy := sourcesdk.NewOffset([]byte("test-topic*100*2"), "1")
+	legacyOffset, err := ToKafkaOffset(&legacy)
+	assert.NoError(t, err)
+	assert.Equal(t, kafkaOffset, legacyOffset)
+
+	invalid := sourcesdk.NewOffset([]byte("test-topic*100*x"), "0")
+	_, err = ToKafkaOffset(&invalid)
+	assert.Error(t, err)
+}
+
+func TestKafkaOffsetString(t *testing.T) {
+	assert.Equal(t, "test-topic:1:100", (&KafkaOffset{topic: "test-topic", partitionIdx: 1, offset: 100}).String())
+	assert.Equal(t, "test-topic:1:100:2", (&KafkaOffset{topic: "test-topic", partitionIdx: 1, offset: 100, subIndex: 2, hasSubIndex: true}).String())
+}
+
+func TestOffsetEncoding_OptionalFields(t *testing.T) {
+	original := &KafkaOffset{
+		offset:         100,
+		partitionIdx:   3,
+		topic:          "test-topic",
+		leaderEpoch:    7,
+		hasLeaderEpoch: true,
+	}
+	srcOffset := original.ToSourceOffset()
+	decoded, err := ToKafkaOffset(&srcOffset)
+	assert.NoError(t, err)
+	assert.Equal(t, original, decoded)
+	epoch, ok := decoded.LeaderEpoch()
+	assert.True(t, ok)
+	assert.Equal(t, int32(7), epoch)
+	_, ok = decoded.SubIndex()
+	assert.False(t, ok)
+}
+
+func TestOffsetEncoding_Malformed(t *testing.T) {
+	valid := (&KafkaOffset{offset: 100, partitionIdx: 3, topic: "test-topic"}).ToSourceOffset()
+	tests := []struct {
+		name        string
+		value       []byte
+		partitionID string
+		err         string
+	}{
+		{name: "truncated", value: valid.Value()[:len(valid.Value())-1], partitionID: "3", err: "invalid offset"},
+		{name: "trailing bytes", value: append(append([]byte{}, valid.Value()...), 0), partitionID: "3", err: "1 trailing bytes"},
+		{name: "unknown fields", value: []byte{offsetEncodingV1, 0x08, 0x00, 0x00, 0x00}, partitionID: "0", err: "unknown fields 0x8"},
+		// the third bit was reserved for the consumer group generation, which was never written
+		{name: "generation", value: []byte{offsetEncodingV1, 0x04, 0x00, 0x00, 0x00, 0x02}, partitionID: "0", err: "unknown fields 0x4"},
+		{name: "topic too long", value: []byte{offsetEncodingV1, 0x00, 0x7f, 'a'}, partitionID: "0", err: "topic length 127 exceeds the 1 remaining bytes"},
+		{name: "other partition", value: valid.Value(), partitionID: "4", err: "the offset value is of partition 3"},
+		{name: "empty", value: nil, partitionID: "0", err: "cannot be divided to topic and offset"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := sourcesdk.NewOffset(tt.value, tt.partitionID)
+			_, err := ToKafkaOffset(&o)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func FuzzOffsetRoundTrip(f *testing.F) {
+	f.Add("test-topic", int32(0), int64(100), int32(0), int32(0), uint8(0))
+	f.Add("test-topic", int32(3), int64(-1), int32(2), int32(7), uint8(3))
+	f.Add("", int32(-1), int64(1)<<62, int32(-5), int32(0), uint8(1))
+	f.Fuzz(func(t *testing.T, topic string, partition int32, offset int64, subIndex, leaderEpoch int32, fields uint8) {
+		original := &KafkaOffset{offset: offset, partitionIdx: partition, topic: topic}
+		if fields&1 != 0 {
+			original.subIndex, original.hasSubIndex = subIndex, true
+		}
+		if fields&2 != 0 {
+			original.leaderEpoch, original.hasLeaderEpoch = leaderEpoch, true
+		}
+		srcOffset := original.ToSourceOffset()
+		decoded, err := ToKafkaOffset(&srcOffset)
+		if err != nil {
+			t.Fatalf("failed to decode %v, %v", original, err)
+		}
+		assert.Equal(t, original, decoded)
+	})
+}
+
+func FuzzToKafkaOffset(f *testing.F) {
+	f.Add([]byte("test-topic*100"), "0")
+	f.Add([]byte("test-topic*100*2"), "1")
+	f.Add((&KafkaOffset{offset: 100, partitionIdx: 3, topic: "test-topic", subIndex: 1, hasSubIndex: true}).encode(), "3")
+	f.Add([]byte{offsetEncodingV1, 0xff, 0xff, 0xff}, "0")
+	f.Fuzz(func(t *testing.T, value []byte, partitionID string) {
+		o := sourcesdk.NewOffset(value, partitionID)
+		k, err := ToKafkaOffset(&o)
+		if err != nil {
+			return
+		}
+		// whatever parses re-encodes to an offset that decodes to the same value.
+		srcOffset := k.ToSourceOffset()
+		decoded, err := ToKafkaOffset(&srcOffset)
+		if err != nil {
+			t.Fatalf("failed to decode the re-encoded %v, %v", k, err)
+		}
+		assert.Equal(t, k, decoded)
+	})
 }
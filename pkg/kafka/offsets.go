@@ -0,0 +1,259 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+// PartitionOffsets holds the offsets of a partition of the topic, and the committed offset of the consumer group.
+type PartitionOffsets struct {
+	Partition int32 `json:"partition"`
+	// Committed is the committed offset of the consumer group, -1 if there is none.
+	Committed int64 `json:"committed"`
+	// LogStart is the offset of the oldest record of the partition.
+	LogStart int64 `json:"logStart"`
+	// LogEnd is the offset of the next record produced to the partition.
+	LogEnd int64 `json:"logEnd"`
+	// Lag is the number of records the consumer group is still to process.
+	Lag int64 `json:"lag"`
+}
+
+// position returns the offset the consumer group resumes consuming the partition from.
+func (p PartitionOffsets) position() int64 {
+	return p.LogEnd - p.Lag
+}
+
+// ResetStrategy tells which offset the consumer group is reset to.
+// +enum
+type ResetStrategy string
+
+const (
+	// ResetToEarliest resets to the oldest offset of every partition
+	ResetToEarliest ResetStrategy = "to-earliest"
+	// ResetToLatest resets to the end offset of every partition
+	ResetToLatest ResetStrategy = "to-latest"
+	// ResetToTimestamp resets to the first offset of every partition produced at or after a timestamp
+	ResetToTimestamp ResetStrategy = "to-timestamp"
+	// ResetShiftBy moves the offset of every partition by a number of records, backwards if negative
+	ResetShiftBy ResetStrategy = "shift-by"
+	// ResetToExplicit resets every partition to the same offset
+	ResetToExplicit ResetStrategy = "to-explicit"
+)
+
+// OffsetReset is the reset of the committed offset of a partition.
+type OffsetReset struct {
+	Partition int32 `json:"partition"`
+	// Current is the offset the consumer group resumes consuming the partition from before the reset.
+	Current int64 `json:"current"`
+	Target  int64 `json:"target"`
+}
+
+// OffsetsAdmin inspects and resets the committed offsets of the consumer group of the source.
+type OffsetsAdmin struct {
+	client sarama.Client
+	admin  sarama.ClusterAdmin
+	topic  string
+	group  string
+}
+
+// NewOffsetsAdmin connects to the brokers of the source configured by c, with the sarama config built by
+// NewSaramaConfig.
+func NewOffsetsAdmin(c *config.Config, saramaConfig *sarama.Config) (*OffsetsAdmin, error) {
+	client, err := sarama.NewClient(c.Brokers, saramaConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sarama client, %w", err)
+	}
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to create sarama cluster admin client, %w", err)
+	}
+	return &OffsetsAdmin{
+		client: client,
+		admin:  admin,
+		topic:  c.Topic,
+		group:  c.ConsumerGroupName,
+	}, nil
+}
+
+// Close closes the admin client and the underlying sarama client.
+func (a *OffsetsAdmin) Close() error {
+	return a.admin.Close()
+}
+
+// Describe returns the offsets of the given partitions, or of all the partitions of the topic if there are none.
+func (a *OffsetsAdmin) Describe(partitions []int32) ([]PartitionOffsets, error) {
+	partitions, err := a.partitions(partitions)
+	if err != nil {
+		return nil, err
+	}
+	rep, err := a.admin.ListConsumerGroupOffsets(a.group, map[string][]int32{a.topic: partitions})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consumer group offsets, %w", err)
+	}
+	version := a.client.Config().Version
+	oldest, err := listOffsets(a.client, version, a.topic, partitions, sarama.OffsetOldest)
+	if err != nil {
+		return nil, err
+	}
+	newest, err := listOffsets(a.client, version, a.topic, partitions, sarama.OffsetNewest)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]PartitionOffsets, 0, len(partitions))
+	for _, partition := range partitions {
+		block := rep.GetBlock(a.topic, partition)
+		if block == nil {
+			return nil, fmt.Errorf("no offset returned for partition %d", partition)
+		}
+		if !errors.Is(block.Err, sarama.ErrNoError) {
+			return nil, fmt.Errorf("failed to fetch the committed offset of partition %d, %w", partition, block.Err)
+		}
+		result = append(result, PartitionOffsets{
+			Partition: partition,
+			Committed: block.Offset,
+			LogStart:  oldest[partition],
+			LogEnd:    newest[partition],
+			Lag:       partitionLag(block.Offset, oldest[partition], newest[partition], a.client.Config().Consumer.Offsets.Initial),
+		})
+	}
+	return result, nil
+}
+
+// Plan computes the reset of the given partitions, or of all the partitions of the topic if there are none. The
+// timestamp is only used to reset to a timestamp, and the value to shift by or to reset to an explicit offset.
+func (a *OffsetsAdmin) Plan(strategy ResetStrategy, timestamp time.Time, value int64, partitions []int32) ([]OffsetReset, error) {
+	offsets, err := a.Describe(partitions)
+	if err != nil {
+		return nil, err
+	}
+	var atTimestamp map[int32]int64
+	if strategy == ResetToTimestamp {
+		ps := make([]int32, 0, len(offsets))
+		for _, o := range offsets {
+			ps = append(ps, o.Partition)
+		}
+		if atTimestamp, err = listOffsets(a.client, a.client.Config().Version, a.topic, ps, timestamp.UnixMilli()); err != nil {
+			return nil, err
+		}
+	}
+	return planReset(strategy, value, offsets, atTimestamp)
+}
+
+// Apply commits the target offsets of a reset plan. The consumer group must not have any active member, otherwise the
+// members would overwrite the offsets with their next commit.
+func (a *OffsetsAdmin) Apply(plan []OffsetReset) error {
+	groups, err := a.admin.DescribeConsumerGroups([]string{a.group})
+	if err != nil {
+		return fmt.Errorf("failed to describe consumer group %s, %w", a.group, err)
+	}
+	if len(groups) == 1 && groups[0].State != "Empty" && groups[0].State != "Dead" {
+		return fmt.Errorf("consumer group %s is %s with %d members, stop the sources consuming with it before resetting its offsets", a.group, groups[0].State, len(groups[0].Members))
+	}
+	coordinator, err := a.client.Coordinator(a.group)
+	if err != nil {
+		return fmt.Errorf("failed to find the coordinator of consumer group %s, %w", a.group, err)
+	}
+	request := &sarama.OffsetCommitRequest{
+		Version:                 offsetCommitRequestVersion(a.client.Config().Version),
+		ConsumerGroup:           a.group,
+		ConsumerGroupGeneration: sarama.GroupGenerationUndefined,
+		RetentionTime:           -1,
+	}
+	for _, r := range plan {
+		request.AddBlock(a.topic, r.Partition, r.Target, 0, "")
+	}
+	response, err := coordinator.CommitOffset(request)
+	if err != nil {
+		return fmt.Errorf("failed to commit offsets, %w", err)
+	}
+	for _, r := range plan {
+		if kErr := response.Errors[a.topic][r.Partition]; !errors.Is(kErr, sarama.ErrNoError) {
+			return fmt.Errorf("failed to commit the offset of partition %d, %w", r.Partition, kErr)
+		}
+	}
+	return nil
+}
+
+// partitions returns the partitions sorted, or all the partitions of the topic if there are none.
+func (a *OffsetsAdmin) partitions(partitions []int32) ([]int32, error) {
+	all, err := a.client.Partitions(a.topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions of topic %s, %w", a.topic, err)
+	}
+	if len(partitions) == 0 {
+		partitions = all
+	}
+	known := make(map[int32]struct{}, len(all))
+	for _, p := range all {
+		known[p] = struct{}{}
+	}
+	result := make([]int32, 0, len(partitions))
+	for _, p := range partitions {
+		if _, ok := known[p]; !ok {
+			return nil, fmt.Errorf("topic %s has no partition %d", a.topic, p)
+		}
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+	return result, nil
+}
+
+// planReset computes the target offset of every partition, within the offsets the partition holds. atTimestamp are
+// the offsets of the first records at the reset timestamp, -1 if there is none.
+func planReset(strategy ResetStrategy, value int64, offsets []PartitionOffsets, atTimestamp map[int32]int64) ([]OffsetReset, error) {
+	plan := make([]OffsetReset, 0, len(offsets))
+	for _, o := range offsets {
+		var target int64
+		switch strategy {
+		case ResetToEarliest:
+			target = o.LogStart
+		case ResetToLatest:
+			target = o.LogEnd
+		case ResetToTimestamp:
+			target = atTimestamp[o.Partition]
+			if target == -1 {
+				// nothing was produced since the timestamp.
+				target = o.LogEnd
+			}
+		case ResetShiftBy:
+			target = o.position() + value
+		case ResetToExplicit:
+			target = value
+		default:
+			return nil, fmt.Errorf("failed to parse reset strategy %q. Must be one of the following: ['%s', '%s', '%s', '%s', '%s']", strategy, ResetToEarliest, ResetToLatest, ResetToTimestamp, ResetShiftBy, ResetToExplicit)
+		}
+		if target < o.LogStart {
+			target = o.LogStart
+		}
+		if target > o.LogEnd {
+			target = o.LogEnd
+		}
+		plan = append(plan, OffsetReset{
+			Partition: o.Partition,
+			Current:   o.position(),
+			Target:    target,
+		})
+	}
+	return plan, nil
+}
+
+// offsetCommitRequestVersion returns the OffsetCommit request version supported by the kafka version, as sarama does.
+func offsetCommitRequestVersion(version sarama.KafkaVersion) int16 {
+	switch {
+	case version.IsAtLeast(sarama.V2_0_0_0):
+		return 4
+	case version.IsAtLeast(sarama.V0_11_0_0):
+		return 3
+	default:
+		return 2
+	}
+}
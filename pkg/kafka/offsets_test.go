@@ -0,0 +1,137 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanReset(t *testing.T) {
+	offsets := []PartitionOffsets{
+		{Partition: 0, Committed: 50, LogStart: 10, LogEnd: 100, Lag: 50},
+		// no committed offset, consumed from the oldest offset
+		{Partition: 1, Committed: -1, LogStart: 20, LogEnd: 200, Lag: 180},
+	}
+	tests := []struct {
+		name     string
+		strategy ResetStrategy
+		value    int64
+		targets  []int64
+	}{
+		{name: "earliest", strategy: ResetToEarliest, targets: []int64{10, 20}},
+		{name: "latest", strategy: ResetToLatest, targets: []int64{100, 200}},
+		{name: "timestamp", strategy: ResetToTimestamp, targets: []int64{70, 200}},
+		{name: "shift backwards", strategy: ResetShiftBy, value: -30, targets: []int64{20, 20}},
+		{name: "shift forwards", strategy: ResetShiftBy, value: 60, targets: []int64{100, 80}},
+		{name: "explicit", strategy: ResetToExplicit, value: 150, targets: []int64{100, 150}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := planReset(tt.strategy, tt.value, offsets, map[int32]int64{0: 70, 1: -1})
+			assert.NoError(t, err)
+			assert.Equal(t, []OffsetReset{
+				{Partition: 0, Current: 50, Target: tt.targets[0]},
+				{Partition: 1, Current: 20, Target: tt.targets[1]},
+			}, plan)
+		})
+	}
+
+	_, err := planReset("to-nowhere", 0, offsets, nil)
+	assert.ErrorContains(t, err, "failed to parse reset strategy")
+}
+
+func newOffsetsAdmin(t *testing.T, broker *sarama.MockBroker) *OffsetsAdmin {
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_1_0_0
+	config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	client, err := sarama.NewClient([]string{broker.Addr()}, config)
+	assert.NoError(t, err)
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	assert.NoError(t, err)
+	return &OffsetsAdmin{client: client, admin: admin, topic: "test-topic", group: "test-group"}
+}
+
+func offsetsHandlers(t *testing.T, broker *sarama.MockBroker) map[string]sarama.MockResponse {
+	return map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetController(broker.BrokerID()).
+			SetLeader("test-topic", 0, broker.BrokerID()).
+			SetLeader("test-topic", 1, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("test-topic", 0, sarama.OffsetOldest, 10).
+			SetOffset("test-topic", 0, sarama.OffsetNewest, 100).
+			SetOffset("test-topic", 1, sarama.OffsetOldest, 20).
+			SetOffset("test-topic", 1, sarama.OffsetNewest, 200),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "test-group", broker),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset("test-group", "test-topic", 0, 50, "", sarama.ErrNoError).
+			SetOffset("test-group", "test-topic", 1, -1, "", sarama.ErrNoError),
+		"DescribeGroupsRequest": sarama.NewMockDescribeGroupsResponse(t).
+			AddGroupDescription("test-group", &sarama.GroupDescription{GroupId: "test-group", State: "Empty"}),
+		"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t),
+	}
+}
+
+func TestOffsetsAdmin_Describe(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(offsetsHandlers(t, broker))
+	a := newOffsetsAdmin(t, broker)
+	defer a.Close()
+
+	offsets, err := a.Describe(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []PartitionOffsets{
+		{Partition: 0, Committed: 50, LogStart: 10, LogEnd: 100, Lag: 50},
+		{Partition: 1, Committed: -1, LogStart: 20, LogEnd: 200, Lag: 180},
+	}, offsets)
+
+	_, err = a.Describe([]int32{2})
+	assert.ErrorContains(t, err, "topic test-topic has no partition 2")
+}
+
+func TestOffsetsAdmin_Apply(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(offsetsHandlers(t, broker))
+	a := newOffsetsAdmin(t, broker)
+	defer a.Close()
+
+	plan, err := a.Plan(ResetToEarliest, time.Time{}, 0, []int32{0})
+	assert.NoError(t, err)
+	assert.Equal(t, []OffsetReset{{Partition: 0, Current: 50, Target: 10}}, plan)
+	assert.NoError(t, a.Apply(plan))
+
+	var commits []*sarama.OffsetCommitRequest
+	for _, r := range broker.History() {
+		if request, ok := r.Request.(*sarama.OffsetCommitRequest); ok {
+			commits = append(commits, request)
+		}
+	}
+	assert.Len(t, commits, 1)
+	offset, _, err := commits[0].Offset("test-topic", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), offset)
+}
+
+func TestOffsetsAdmin_ApplyWithActiveMembers(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	handlers := offsetsHandlers(t, broker)
+	handlers["DescribeGroupsRequest"] = sarama.NewMockDescribeGroupsResponse(t).
+		AddGroupDescription("test-group", &sarama.GroupDescription{
+			GroupId: "test-group",
+			State:   "Stable",
+			Members: map[string]*sarama.GroupMemberDescription{"member-1": {}},
+		})
+	broker.SetHandlerByMap(handlers)
+	a := newOffsetsAdmin(t, broker)
+	defer a.Close()
+
+	err := a.Apply([]OffsetReset{{Partition: 0, Current: 50, Target: 10}})
+	assert.ErrorContains(t, err, "consumer group test-group is Stable with 1 members")
+}
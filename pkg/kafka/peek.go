@@ -0,0 +1,121 @@
+package kafka
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/IBM/sarama"
+	sourcesdk "github.com/numaproj/numaflow-go/pkg/sourcer"
+	"go.uber.org/zap"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+const (
+	peekValueUTF8   = "utf-8"
+	peekValueBase64 = "base64"
+)
+
+// PeekedMessage is a message the source would emit for the records read by Peek.
+type PeekedMessage struct {
+	Partition int32 `json:"partition"`
+	// Offset is the offset of the record, the highest one for an aggregated message.
+	Offset int64 `json:"offset"`
+	// SourceOffset is the encoded offset of the SDK message, the one the message is acked with.
+	SourceOffset []byte    `json:"sourceOffset"`
+	Keys         []string  `json:"keys"`
+	EventTime    time.Time `json:"eventTime"`
+	// Value is the payload of the message, as is if it is valid UTF-8 and base64 encoded otherwise.
+	Value         string `json:"value"`
+	ValueEncoding string `json:"valueEncoding"`
+}
+
+// Peek reads up to count records of a partition from an offset, and converts them to the messages the source would
+// emit, with the stages enabled in c. It doesn't join the consumer group and commits nothing. It returns once count
+// records were read, once the end of the partition is reached unless reading from the newest offset, or when ctx is
+// done.
+func Peek(ctx context.Context, c *config.Config, saramaConfig *sarama.Config, partition int32, offset int64, count int, emit func(PeekedMessage) error) error {
+	k := &kafkaSource{
+		topic:   c.Topic,
+		logger:  zap.NewNop(),
+		handler: newConsumerHandler(0),
+		// nothing is tracked, so the stages never mark any offset.
+		tracker: newOffsetTracker(),
+		// no leader epochs: nothing is acked, so there are no acks to fence, and the offsets are encoded without them.
+	}
+	if err := k.initStages(c); err != nil {
+		return err
+	}
+	consumer, err := sarama.NewConsumer(c.Brokers, saramaConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create sarama consumer, %w", err)
+	}
+	defer func() {
+		_ = consumer.Close()
+	}()
+	pc, err := consumer.ConsumePartition(c.Topic, partition, offset)
+	if err != nil {
+		return fmt.Errorf("failed to consume partition %d from offset %d, %w", partition, offset, err)
+	}
+	defer func() {
+		_ = pc.Close()
+	}()
+
+	emitAll := func(msgs []sourcesdk.Message) error {
+		for _, msg := range msgs {
+			p, err := peekedMessage(msg)
+			if err != nil {
+				return err
+			}
+			if err := emit(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for read := 0; read < count; {
+		select {
+		case <-ctx.Done():
+			return emitAll(k.flushAggregates())
+		case err := <-pc.Errors():
+			return fmt.Errorf("failed to read partition %d, %w", partition, err)
+		case m := <-pc.Messages():
+			read++
+			if m, ok := k.prepare(m); ok {
+				if err := emitAll(k.toSDKMessages(m)); err != nil {
+					return err
+				}
+			}
+			if offset != sarama.OffsetNewest && m.Offset+1 >= pc.HighWaterMarkOffset() {
+				// the end of the partition is reached, the next records are not produced yet.
+				count = read
+			}
+		}
+	}
+	return emitAll(k.flushAggregates())
+}
+
+func peekedMessage(msg sourcesdk.Message) (PeekedMessage, error) {
+	sourceOffset := msg.Offset()
+	o, err := ToKafkaOffset(&sourceOffset)
+	if err != nil {
+		return PeekedMessage{}, err
+	}
+	p := PeekedMessage{
+		Partition:     o.PartitionIdx(),
+		Offset:        o.offset,
+		SourceOffset:  sourceOffset.Value(),
+		Keys:          msg.Keys(),
+		EventTime:     msg.EventTime(),
+		Value:         string(msg.Value()),
+		ValueEncoding: peekValueUTF8,
+	}
+	if !utf8.Valid(msg.Value()) {
+		p.Value = base64.StdEncoding.EncodeToString(msg.Value())
+		p.ValueEncoding = peekValueBase64
+	}
+	return p, nil
+}
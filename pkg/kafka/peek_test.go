@@ -0,0 +1,57 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+func TestPeek(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("test-topic", 0, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("test-topic", 0, sarama.OffsetOldest, 0).
+			SetOffset("test-topic", 0, sarama.OffsetNewest, 2),
+		"FetchRequest": sarama.NewMockFetchResponse(t, 2).
+			SetMessage("test-topic", 0, 0, sarama.StringEncoder("{\"a\":1}\n{\"a\":2}")).
+			SetMessage("test-topic", 0, 1, sarama.ByteEncoder{0xff, 0x00}).
+			SetHighWaterMark("test-topic", 0, 2),
+	})
+	c := &config.Config{
+		Brokers: []string{broker.Addr()},
+		Topic:   "test-topic",
+		Split:   &config.Split{Format: config.SplitFormatNDJSON},
+	}
+
+	var peeked []PeekedMessage
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// the end of the partition is reached before the count.
+	err := Peek(ctx, c, sarama.NewConfig(), 0, sarama.OffsetOldest, 10, func(m PeekedMessage) error {
+		peeked = append(peeked, m)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, ctx.Err())
+	assert.Len(t, peeked, 3)
+
+	assert.Equal(t, `{"a":1}`, peeked[0].Value)
+	assert.Equal(t, peekValueUTF8, peeked[0].ValueEncoding)
+	assert.Equal(t, `{"a":2}`, peeked[1].Value)
+	assert.Equal(t, int64(0), peeked[1].Offset)
+	record := &sarama.ConsumerMessage{Topic: "test-topic", Partition: 0, Offset: 0}
+	assert.Equal(t, GenerateSplitSourceSdkOffset(record, unknownLeaderEpoch, 1).Value(), peeked[1].SourceOffset)
+
+	assert.Equal(t, int64(1), peeked[2].Offset)
+	assert.Equal(t, "/wA=", peeked[2].Value)
+	assert.Equal(t, peekValueBase64, peeked[2].ValueEncoding)
+}
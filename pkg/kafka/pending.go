@@ -0,0 +1,273 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"go.uber.org/zap"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/metrics"
+)
+
+const defaultPendingRefreshInterval = 5 * time.Second
+
+// pendingMaxAgeRefreshes is the number of refresh intervals after which the last computed pending messages are too old
+// to be reported, e.g. while the brokers are unreachable.
+const pendingMaxAgeRefreshes = 3
+
+// pendingMessages breaks the pending messages down by where they are.
+type pendingMessages struct {
+	total int64
+	// consumed from the broker, waiting in the handler buffer to be read or in the backlog of the split and aggregated
+	// messages to be emitted
+	buffered int64
+	// still on the broker, or read but not committed yet
+	broker int64
+}
+
+// partitionReport holds the offsets of a partition used to compute its pending messages.
+type partitionReport struct {
+	// committed offset of the consumer group, -1 if there is none
+	committed int64
+	// offset the consumer group resumes consuming the partition from
+	start int64
+	// offset of the next record produced to the partition
+	newest int64
+	lag    int64
+	// how far the record at the start offset trails the newest record, only computed if the time lag is enabled
+	timeLag time.Duration
+}
+
+// lagReport is the result of the last pending messages computation.
+type lagReport struct {
+	total      int64
+	partitions map[int32]*partitionReport
+	computedAt time.Time
+}
+
+// pendingCache holds the last lag report computed by the background refresher.
+type pendingCache struct {
+	lock   sync.RWMutex
+	report *lagReport
+}
+
+func (c *pendingCache) set(r *lagReport) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.report = r
+}
+
+func (c *pendingCache) get() *lagReport {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.report
+}
+
+// cachedPending returns the last computed pending messages along with their age, and false if they were never computed
+// or if they are too old to be reported.
+func (k *kafkaSource) cachedPending() (*pendingMessages, time.Duration, bool) {
+	r := k.pendingCache.get()
+	if r == nil {
+		return nil, 0, false
+	}
+	age := time.Since(r.computedAt)
+	if age > pendingMaxAgeRefreshes*k.pendingRefreshInterval {
+		return nil, age, false
+	}
+	return breakDownPending(r.total, int64(len(k.handler.messages))+k.backlogSize.Load()), age, true
+}
+
+// runPendingRefresher computes the pending messages on every interval until ctx is done, when the source is closed or
+// reloaded. A failed refresh keeps the previous value, so that transient errors don't make the pending messages
+// unavailable, until it is too old to be reported.
+func (k *kafkaSource) runPendingRefresher(ctx context.Context) {
+	ticker := time.NewTicker(k.pendingRefreshInterval)
+	defer ticker.Stop()
+	for {
+		start := time.Now()
+		if r, err := k.refreshLag(); err != nil {
+			k.logger.Warn("Failed to refresh pending messages, keeping the previous value", zap.Error(err))
+		} else {
+			if k.timeLag != nil {
+				k.computeTimeLag(r, k.pendingCache.get())
+			}
+			k.pendingCache.set(r)
+			k.exportLag(r)
+		}
+		metrics.LagRefreshDuration.WithLabelValues(k.topic).Observe(time.Since(start).Seconds())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// exportLag exports the per partition offsets and lag of a report as metrics.
+func (k *kafkaSource) exportLag(r *lagReport) {
+	for partition, p := range r.partitions {
+		labels := []string{k.topic, strconv.Itoa(int(partition))}
+		metrics.CommittedOffset.WithLabelValues(labels...).Set(float64(p.committed))
+		metrics.PartitionLag.WithLabelValues(labels...).Set(float64(p.lag))
+		if k.timeLag != nil {
+			metrics.PartitionTimeLag.WithLabelValues(labels...).Set(p.timeLag.Seconds())
+		}
+	}
+}
+
+// refreshLag computes the lag with the clients of the source, which Close may be closing.
+func (k *kafkaSource) refreshLag() (*lagReport, error) {
+	k.reloadLock.RLock()
+	defer k.reloadLock.RUnlock()
+	if k.lifecycleCtx != nil && k.lifecycleCtx.Err() != nil {
+		return nil, fmt.Errorf("the source is closed")
+	}
+	return k.computeLag()
+}
+
+// computeLag computes the number of messages that are still to be processed by the consumer group, with one
+// ListConsumerGroupOffsets request and one ListOffsets request per leader broker.
+func (k *kafkaSource) computeLag() (*lagReport, error) {
+	if k.adminClient == nil || k.saramaClient == nil {
+		return nil, fmt.Errorf("kafka clients are not ready")
+	}
+	partitions, err := k.saramaClient.Partitions(k.topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions, %w", err)
+	}
+	rep, err := k.adminClient.ListConsumerGroupOffsets(k.consumerGrpName, map[string][]int32{k.topic: partitions})
+	if err != nil {
+		// the admin client looks the coordinator of the group up with the sarama client, the next refresh asks the new one.
+		if rErr := k.saramaClient.RefreshCoordinator(k.consumerGrpName); rErr != nil {
+			k.logger.Warn("Failed to refresh the consumer group coordinator", zap.Error(rErr))
+		}
+		return nil, fmt.Errorf("failed to list consumer group offsets, %w", err)
+	}
+	newest, err := listOffsets(k.saramaClient, k.config.Version, k.topic, partitions, sarama.OffsetNewest)
+	if err != nil {
+		return nil, err
+	}
+	var uncommitted []int32
+	for _, partition := range partitions {
+		block := rep.GetBlock(k.topic, partition)
+		if block == nil {
+			return nil, fmt.Errorf("no offset returned for partition %d", partition)
+		}
+		if block.Offset == -1 {
+			uncommitted = append(uncommitted, partition)
+		}
+	}
+	oldest := map[int32]int64{}
+	if len(uncommitted) > 0 && k.config.Consumer.Offsets.Initial == sarama.OffsetOldest {
+		// Note: if there is no offset associated with the partition under the consumer group, offset fetch sets the offset field to -1.
+		// The group will start consuming the partition from the initial offset, which is the oldest one here.
+		if oldest, err = listOffsets(k.saramaClient, k.config.Version, k.topic, uncommitted, sarama.OffsetOldest); err != nil {
+			return nil, err
+		}
+	}
+	r := &lagReport{
+		partitions: make(map[int32]*partitionReport, len(partitions)),
+		computedAt: time.Now(),
+	}
+	for _, partition := range partitions {
+		committed := rep.GetBlock(k.topic, partition).Offset
+		lag := partitionLag(committed, oldest[partition], newest[partition], k.config.Consumer.Offsets.Initial)
+		r.partitions[partition] = &partitionReport{
+			committed: committed,
+			start:     newest[partition] - lag,
+			newest:    newest[partition],
+			lag:       lag,
+		}
+		r.total += lag
+	}
+	return r, nil
+}
+
+// listOffsets returns the offsets of the partitions at the given time, which can be sarama.OffsetNewest or
+// sarama.OffsetOldest. It sends one request to the leader of each group of partitions.
+func listOffsets(client sarama.Client, version sarama.KafkaVersion, topic string, partitions []int32, at int64) (map[int32]int64, error) {
+	requests := make(map[*sarama.Broker]*sarama.OffsetRequest)
+	requested := make(map[*sarama.Broker][]int32)
+	for _, partition := range partitions {
+		leader, err := client.Leader(topic, partition)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the leader of partition %d, %w", partition, err)
+		}
+		request, ok := requests[leader]
+		if !ok {
+			request = &sarama.OffsetRequest{Version: offsetRequestVersion(version)}
+			requests[leader] = request
+		}
+		request.AddBlock(topic, partition, at, 1)
+		requested[leader] = append(requested[leader], partition)
+	}
+
+	result := make(map[int32]int64, len(partitions))
+	for broker, request := range requests {
+		response, err := broker.GetAvailableOffsets(request)
+		if err != nil {
+			_ = broker.Close()
+			return nil, fmt.Errorf("failed to list offsets from broker %d, %w", broker.ID(), err)
+		}
+		for _, partition := range requested[broker] {
+			block := response.GetBlock(topic, partition)
+			if block == nil {
+				return nil, fmt.Errorf("no offset returned for partition %d, %w", partition, sarama.ErrIncompleteResponse)
+			}
+			if !errors.Is(block.Err, sarama.ErrNoError) {
+				return nil, fmt.Errorf("failed to list offsets of partition %d, %w", partition, block.Err)
+			}
+			if len(block.Offsets) != 1 {
+				return nil, fmt.Errorf("failed to list offsets of partition %d, %w", partition, sarama.ErrOffsetOutOfRange)
+			}
+			result[partition] = block.Offsets[0]
+		}
+	}
+	return result, nil
+}
+
+// offsetRequestVersion returns the ListOffsets request version supported by the kafka version, as sarama does.
+func offsetRequestVersion(version sarama.KafkaVersion) int16 {
+	switch {
+	case version.IsAtLeast(sarama.V2_1_0_0):
+		return 4
+	case version.IsAtLeast(sarama.V2_0_0_0):
+		return 3
+	case version.IsAtLeast(sarama.V0_11_0_0):
+		return 2
+	case version.IsAtLeast(sarama.V0_10_1_0):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// partitionLag returns the number of messages of a partition that are still to be processed by the consumer group.
+// A partition without a committed offset (-1) is consumed from the initial offset of the group: its whole content is
+// pending if the group starts from the oldest offset, and nothing is if it starts from the newest one.
+func partitionLag(committed, oldest, newest, initial int64) int64 {
+	if committed == -1 {
+		if initial == sarama.OffsetOldest {
+			return newest - oldest
+		}
+		return 0
+	}
+	return newest - committed
+}
+
+// breakDownPending splits the total pending messages into the ones buffered in the handler and the rest.
+func breakDownPending(total, buffered int64) *pendingMessages {
+	if buffered > total {
+		buffered = total
+	}
+	return &pendingMessages{
+		total:    total,
+		buffered: buffered,
+		broker:   total - buffered,
+	}
+}
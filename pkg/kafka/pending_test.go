@@ -0,0 +1,159 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	sourcesdk "github.com/numaproj/numaflow-go/pkg/sourcer"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+	"github.com/numaproj-contrib/kafka-source-go/pkg/metrics"
+)
+
+func TestPartitionLag(t *testing.T) {
+	assert.Equal(t, int64(30), partitionLag(70, 10, 100, sarama.OffsetNewest))
+	assert.Equal(t, int64(30), partitionLag(70, 10, 100, sarama.OffsetOldest))
+	// no committed offset yet
+	assert.Equal(t, int64(90), partitionLag(-1, 10, 100, sarama.OffsetOldest))
+	assert.Equal(t, int64(0), partitionLag(-1, 10, 100, sarama.OffsetNewest))
+}
+
+func TestBreakDownPending(t *testing.T) {
+	assert.Equal(t, &pendingMessages{total: 100, buffered: 10, broker: 90}, breakDownPending(100, 10))
+	// the buffer may hold messages that were already accounted as consumed
+	assert.Equal(t, &pendingMessages{total: 5, buffered: 5, broker: 0}, breakDownPending(5, 10))
+}
+
+func TestListOffsets(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("test-topic", 0, broker.BrokerID()).
+			SetLeader("test-topic", 1, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("test-topic", 0, sarama.OffsetNewest, 100).
+			SetOffset("test-topic", 1, sarama.OffsetNewest, 200),
+	})
+
+	config := sarama.NewConfig()
+	client, err := sarama.NewClient([]string{broker.Addr()}, config)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	offsets, err := listOffsets(client, config.Version, "test-topic", []int32{0, 1}, sarama.OffsetNewest)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int32]int64{0: 100, 1: 200}, offsets)
+
+	offsetRequests := 0
+	for _, r := range broker.History() {
+		if _, ok := r.Request.(*sarama.OffsetRequest); ok {
+			offsetRequests++
+		}
+	}
+	// both partitions are listed with a single request to their leader
+	assert.Equal(t, 1, offsetRequests)
+}
+
+func TestCachedPending_Expires(t *testing.T) {
+	k := &kafkaSource{
+		handler:                newConsumerHandler(1),
+		pendingCache:           &pendingCache{},
+		pendingRefreshInterval: time.Second,
+	}
+	_, _, ok := k.cachedPending()
+	assert.False(t, ok)
+
+	k.pendingCache.set(&lagReport{total: 10, computedAt: time.Now().Add(-2 * time.Second)})
+	p, _, ok := k.cachedPending()
+	assert.True(t, ok)
+	assert.Equal(t, int64(10), p.total)
+
+	// the brokers could not be reached for more than 3 refreshes
+	k.pendingCache.set(&lagReport{total: 10, computedAt: time.Now().Add(-4 * time.Second)})
+	_, age, ok := k.cachedPending()
+	assert.False(t, ok)
+	assert.Greater(t, age, 3*time.Second)
+}
+
+func TestCachedPending_CountsBacklog(t *testing.T) {
+	s, err := newSplitter(&config.Split{Format: config.SplitFormatJSONArray})
+	assert.NoError(t, err)
+	k := &kafkaSource{
+		topic:                  "test-topic",
+		handler:                newConsumerHandler(2),
+		tracker:                newOffsetTracker(),
+		health:                 newConsumerHealth(nil),
+		splitter:               s,
+		pendingCache:           &pendingCache{},
+		pendingRefreshInterval: time.Minute,
+		logger:                 zap.NewNop(),
+	}
+	k.handler.messages <- &sarama.ConsumerMessage{Topic: "test-topic", Offset: 0, Value: []byte(`[1, 2, 3]`)}
+	k.handler.messages <- &sarama.ConsumerMessage{Topic: "test-topic", Offset: 1, Value: []byte(`[4]`)}
+	messageCh := make(chan sourcesdk.Message, 1)
+	k.Read(context.Background(), readRequest{count: 1, timeout: 50 * time.Millisecond}, messageCh)
+
+	// two split messages wait in the backlog, and a record in the buffer
+	k.pendingCache.set(&lagReport{total: 10, computedAt: time.Now()})
+	p, _, ok := k.cachedPending()
+	assert.True(t, ok)
+	assert.Equal(t, &pendingMessages{total: 10, buffered: 3, broker: 7}, p)
+}
+
+func TestComputeLag_KeepsAdminClient(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetController(broker.BrokerID()).
+			SetLeader("test-topic", 0, broker.BrokerID()),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetError(sarama.CoordinatorGroup, "test-group", sarama.ErrGroupAuthorizationFailed),
+	})
+	config := sarama.NewConfig()
+	config.Metadata.Retry.Max = 0
+	client, err := sarama.NewClient([]string{broker.Addr()}, config)
+	assert.NoError(t, err)
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	assert.NoError(t, err)
+	defer admin.Close()
+
+	k := &kafkaSource{
+		topic:           "test-topic",
+		consumerGrpName: "test-group",
+		config:          config,
+		saramaClient:    client,
+		adminClient:     admin,
+		logger:          zap.NewNop(),
+	}
+	_, err = k.refreshLag()
+	assert.ErrorIs(t, err, sarama.ErrGroupAuthorizationFailed)
+	// the coordinator is looked up again by the next refresh, with the same admin client
+	assert.Same(t, admin, k.adminClient)
+}
+
+func TestPending_ExportsAge(t *testing.T) {
+	k := &kafkaSource{
+		topic:                  "test-age-topic",
+		handler:                newConsumerHandler(1),
+		pendingCache:           &pendingCache{},
+		pendingRefreshInterval: time.Second,
+		logger:                 zap.NewNop(),
+	}
+	k.pendingCache.set(&lagReport{total: 10, computedAt: time.Now().Add(-2 * time.Second)})
+	assert.Equal(t, int64(10), k.Pending(context.Background()))
+	assert.GreaterOrEqual(t, testutil.ToFloat64(metrics.PendingAge.WithLabelValues("test-age-topic")), 2.0)
+
+	// the age keeps growing once the pending messages are too old to be reported
+	k.pendingCache.set(&lagReport{total: 10, computedAt: time.Now().Add(-5 * time.Second)})
+	assert.Equal(t, int64(pendingNotAvailable), k.Pending(context.Background()))
+	assert.GreaterOrEqual(t, testutil.ToFloat64(metrics.PendingAge.WithLabelValues("test-age-topic")), 5.0)
+}
@@ -0,0 +1,184 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/IBM/sarama"
+	"go.uber.org/zap"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+// authorizedOperationsOmitted is the value of the authorized operations when the broker doesn't report them.
+const authorizedOperationsOmitted = math.MinInt32
+
+// unavailableError is a preflight problem of the brokers or the coordinator that may go away by retrying, such as a
+// connection failure, unlike a missing topic or missing ACLs.
+type unavailableError struct {
+	error
+}
+
+func (e unavailableError) Unwrap() error {
+	return e.error
+}
+
+// isUnavailable reports whether the preflight checks failed only because of problems that may go away by retrying.
+func isUnavailable(err error) bool {
+	var unavailable unavailableError
+	return errors.As(err, &unavailable)
+}
+
+// preflight checks that the source can consume the topic with its consumer group, before anything is started.
+func (k *kafkaSource) preflight() error {
+	group := k.consumerGrpName
+	if k.snapshot != nil {
+		// the consumer group is not used in snapshot mode.
+		group = ""
+	}
+	return preflight(k.brokers, k.config, k.topic, group, k.logger)
+}
+
+// Preflight runs the startup checks of the source configured by c, with the sarama config built by NewSaramaConfig.
+func Preflight(c *config.Config, saramaConfig *sarama.Config, logger *zap.Logger) error {
+	group := c.ConsumerGroupName
+	if c.Snapshot != nil {
+		group = ""
+	}
+	return preflight(c.Brokers, saramaConfig, c.Topic, group, logger)
+}
+
+// preflight connects to the brokers and runs the checks. All the problems found are reported in one error, with what
+// to fix.
+func preflight(brokers []string, saramaConfig *sarama.Config, topic, group string, logger *zap.Logger) error {
+	client, err := sarama.NewClient(brokers, saramaConfig)
+	if err != nil {
+		return unavailableError{fmt.Errorf("preflight checks failed, cannot connect to the brokers %v: %w. Check the broker addresses, and the TLS and SASL settings", brokers, err)}
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+	return runPreflight(client, saramaConfig.Version, topic, group, logger)
+}
+
+// runPreflight checks that the topic exists with partitions, and that the consumer group can be described. Where the
+// brokers report the operations the client is authorized to, it checks the ACLs needed to consume the topic with the
+// group as well. An empty group skips the consumer group checks. The error is an unavailableError if all the problems
+// may go away by retrying.
+func runPreflight(client sarama.Client, version sarama.KafkaVersion, topic, group string, logger *zap.Logger) error {
+	var problems []error
+	problems = append(problems, checkTopic(client, version, topic, logger)...)
+	if group != "" {
+		problems = append(problems, checkConsumerGroup(client, version, group, logger)...)
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	unavailable := true
+	for i, problem := range problems {
+		if u, ok := problem.(unavailableError); ok {
+			problems[i] = u.error
+		} else {
+			unavailable = false
+		}
+	}
+	err := fmt.Errorf("preflight checks failed for topic %q:\n%w", topic, errors.Join(problems...))
+	if unavailable {
+		return unavailableError{err}
+	}
+	return err
+}
+
+func checkTopic(client sarama.Client, version sarama.KafkaVersion, topic string, logger *zap.Logger) []error {
+	broker := client.LeastLoadedBroker()
+	if broker == nil {
+		return []error{unavailableError{fmt.Errorf("no broker available to describe topic %q: %w", topic, sarama.ErrOutOfBrokers)}}
+	}
+	request := sarama.NewMetadataRequest(version, []string{topic})
+	if version.IsAtLeast(sarama.V2_3_0_0) {
+		if request.Version < 8 {
+			request.Version = 8
+		}
+		request.IncludeTopicAuthorizedOperations = true
+	}
+	response, err := broker.GetMetadata(request)
+	if err != nil {
+		return []error{unavailableError{fmt.Errorf("failed to describe topic %q: %w. Check the connectivity to broker %s", topic, err, broker.Addr())}}
+	}
+	var metadata *sarama.TopicMetadata
+	for _, t := range response.Topics {
+		if t.Name == topic {
+			metadata = t
+		}
+	}
+	if metadata == nil {
+		return []error{fmt.Errorf("topic %q is missing from the metadata of broker %s", topic, broker.Addr())}
+	}
+	switch {
+	case errors.Is(metadata.Err, sarama.ErrUnknownTopicOrPartition):
+		return []error{fmt.Errorf("topic %q does not exist. Create it or fix the topic name", topic)}
+	case errors.Is(metadata.Err, sarama.ErrTopicAuthorizationFailed):
+		return []error{fmt.Errorf("not authorized to describe topic %q. Grant the Describe and Read ACLs on the topic to the principal of the source", topic)}
+	case !errors.Is(metadata.Err, sarama.ErrNoError):
+		return []error{unavailableError{fmt.Errorf("failed to describe topic %q: %w", topic, metadata.Err)}}
+	case len(metadata.Partitions) == 0:
+		return []error{fmt.Errorf("topic %q has no partitions", topic)}
+	}
+	logger.Info("Preflight: topic found", zap.String("topic", topic), zap.Int("partitions", len(metadata.Partitions)))
+	if !request.IncludeTopicAuthorizedOperations {
+		return nil
+	}
+	return missingOperations(metadata.TopicAuthorizedOperations, "topic", topic)
+}
+
+func checkConsumerGroup(client sarama.Client, version sarama.KafkaVersion, group string, logger *zap.Logger) []error {
+	coordinator, err := client.Coordinator(group)
+	if errors.Is(err, sarama.ErrGroupAuthorizationFailed) {
+		return []error{fmt.Errorf("not authorized to access consumer group %q. Grant the Read and Describe ACLs on the group to the principal of the source", group)}
+	}
+	if err != nil {
+		return []error{unavailableError{fmt.Errorf("failed to find the coordinator of consumer group %q: %w", group, err)}}
+	}
+	request := &sarama.DescribeGroupsRequest{Groups: []string{group}}
+	if version.IsAtLeast(sarama.V2_3_0_0) {
+		request.Version = 3
+		request.IncludeAuthorizedOperations = true
+	}
+	response, err := coordinator.DescribeGroups(request)
+	if err != nil {
+		return []error{unavailableError{fmt.Errorf("failed to describe consumer group %q: %w. Check the connectivity to broker %s", group, err, coordinator.Addr())}}
+	}
+	if len(response.Groups) != 1 {
+		return []error{unavailableError{fmt.Errorf("consumer group %q is missing from the response of broker %s", group, coordinator.Addr())}}
+	}
+	description := response.Groups[0]
+	switch {
+	case errors.Is(description.Err, sarama.ErrGroupAuthorizationFailed):
+		return []error{fmt.Errorf("not authorized to describe consumer group %q. Grant the Read and Describe ACLs on the group to the principal of the source", group)}
+	case !errors.Is(description.Err, sarama.ErrNoError):
+		return []error{unavailableError{fmt.Errorf("failed to describe consumer group %q: %w", group, description.Err)}}
+	}
+	// a group that was never used is reported as dead, the source creates it when joining.
+	logger.Info("Preflight: consumer group described", zap.String("consumerGroupName", group), zap.String("state", description.State), zap.Int("members", len(description.Members)))
+	if !request.IncludeAuthorizedOperations {
+		return nil
+	}
+	return missingOperations(description.AuthorizedOperations, "consumer group", group)
+}
+
+// missingOperations returns a problem for every operation needed to consume that is missing from the authorized
+// operations of a resource, reported as a bit field indexed by sarama.AclOperation.
+func missingOperations(authorized int32, resourceType, name string) []error {
+	if authorized == authorizedOperationsOmitted {
+		return nil
+	}
+	var problems []error
+	for _, op := range []sarama.AclOperation{sarama.AclOperationRead, sarama.AclOperationDescribe} {
+		if authorized&(1<<op) == 0 {
+			problems = append(problems, fmt.Errorf("not authorized to %s %s %q. Grant the %s ACL on the %s to the principal of the source", strings.ToLower(op.String()), resourceType, name, op.String(), resourceType))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,161 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func newPreflightClient(t *testing.T, broker *sarama.MockBroker, version sarama.KafkaVersion) sarama.Client {
+	config := sarama.NewConfig()
+	config.Version = version
+	config.Metadata.Retry.Max = 0
+	// the mock reports the topic errors in the metadata of all the topics too
+	config.Metadata.Full = false
+	client, err := sarama.NewClient([]string{broker.Addr()}, config)
+	assert.NoError(t, err)
+	return client
+}
+
+func TestPreflight(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("test-topic", 0, broker.BrokerID()).
+			SetLeader("test-topic", 1, broker.BrokerID()),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "test-group", broker),
+		// the group was never used and is reported as dead
+		"DescribeGroupsRequest": sarama.NewMockDescribeGroupsResponse(t),
+	})
+	client := newPreflightClient(t, broker, sarama.V2_1_0_0)
+	defer client.Close()
+
+	assert.NoError(t, runPreflight(client, sarama.V2_1_0_0, "test-topic", "test-group", zap.NewNop()))
+}
+
+func TestPreflight_MissingTopicAndGroupAccess(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetError("test-topic", sarama.ErrUnknownTopicOrPartition),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "test-group", broker),
+		"DescribeGroupsRequest": sarama.NewMockDescribeGroupsResponse(t).
+			AddGroupDescription("test-group", &sarama.GroupDescription{
+				GroupId:   "test-group",
+				ErrorCode: int16(sarama.ErrGroupAuthorizationFailed),
+			}),
+	})
+	client := newPreflightClient(t, broker, sarama.V2_1_0_0)
+	defer client.Close()
+
+	err := runPreflight(client, sarama.V2_1_0_0, "test-topic", "test-group", zap.NewNop())
+	// all the problems are reported at once
+	assert.ErrorContains(t, err, `topic "test-topic" does not exist. Create it or fix the topic name`)
+	assert.ErrorContains(t, err, `not authorized to describe consumer group "test-group"`)
+	assert.False(t, isUnavailable(err))
+
+	// the consumer group is not checked in snapshot mode
+	err = runPreflight(client, sarama.V2_1_0_0, "test-topic", "", zap.NewNop())
+	assert.ErrorContains(t, err, "does not exist")
+	assert.NotContains(t, err.Error(), "consumer group")
+}
+
+func TestPreflight_AuthorizedOperations(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	metadata := &sarama.MetadataResponse{Version: 8, ControllerID: broker.BrokerID()}
+	metadata.AddBroker(broker.Addr(), broker.BrokerID())
+	metadata.AddTopicPartition("test-topic", 0, broker.BrokerID(), nil, nil, nil, sarama.ErrNoError)
+	// describe only, read is missing
+	metadata.Topics[0].TopicAuthorizedOperations = 1 << sarama.AclOperationDescribe
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockWrapper(metadata),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "test-group", broker),
+		"DescribeGroupsRequest": sarama.NewMockDescribeGroupsResponse(t).
+			AddGroupDescription("test-group", &sarama.GroupDescription{
+				GroupId:              "test-group",
+				State:                "Stable",
+				AuthorizedOperations: 1<<sarama.AclOperationRead | 1<<sarama.AclOperationDescribe,
+			}),
+	})
+	client := newPreflightClient(t, broker, sarama.V2_3_0_0)
+	defer client.Close()
+
+	err := runPreflight(client, sarama.V2_3_0_0, "test-topic", "test-group", zap.NewNop())
+	assert.ErrorContains(t, err, `not authorized to read topic "test-topic". Grant the Read ACL on the topic`)
+	assert.NotContains(t, err.Error(), "describe")
+	assert.NotContains(t, err.Error(), "consumer group")
+
+	var describeGroups *sarama.DescribeGroupsRequest
+	for _, r := range broker.History() {
+		if req, ok := r.Request.(*sarama.DescribeGroupsRequest); ok {
+			describeGroups = req
+		}
+	}
+	assert.True(t, describeGroups.IncludeAuthorizedOperations)
+}
+
+func TestPreflight_CannotConnect(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	addr := broker.Addr()
+	broker.Close()
+	config := sarama.NewConfig()
+	config.Metadata.Retry.Max = 0
+	config.Net.DialTimeout = 100 * time.Millisecond
+	k := &kafkaSource{
+		brokers:         []string{addr},
+		topic:           "test-topic",
+		consumerGrpName: "test-group",
+		config:          config,
+		logger:          zap.NewNop(),
+	}
+	err := k.preflight()
+	assert.ErrorContains(t, err, "cannot connect to the brokers")
+	assert.ErrorIs(t, err, sarama.ErrOutOfBrokers)
+	// the source starts and retries connecting
+	assert.True(t, isUnavailable(err))
+}
+
+func TestPreflight_CoordinatorUnavailable(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	handlers := map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("test-topic", 0, broker.BrokerID()),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "test-group", broker),
+		"DescribeGroupsRequest": sarama.NewMockDescribeGroupsResponse(t).
+			AddGroupDescription("test-group", &sarama.GroupDescription{
+				GroupId:   "test-group",
+				ErrorCode: int16(sarama.ErrOffsetsLoadInProgress),
+			}),
+	}
+	broker.SetHandlerByMap(handlers)
+	client := newPreflightClient(t, broker, sarama.V2_1_0_0)
+	defer client.Close()
+
+	err := runPreflight(client, sarama.V2_1_0_0, "test-topic", "test-group", zap.NewNop())
+	assert.ErrorIs(t, err, sarama.ErrOffsetsLoadInProgress)
+	assert.True(t, isUnavailable(err))
+
+	// a missing topic fails fast, even with the coordinator unavailable
+	handlers["MetadataRequest"] = sarama.NewMockMetadataResponse(t).
+		SetBroker(broker.Addr(), broker.BrokerID()).
+		SetError("test-topic", sarama.ErrUnknownTopicOrPartition)
+	broker.SetHandlerByMap(handlers)
+	err = runPreflight(client, sarama.V2_1_0_0, "test-topic", "test-group", zap.NewNop())
+	assert.ErrorContains(t, err, "does not exist")
+	assert.ErrorIs(t, err, sarama.ErrOffsetsLoadInProgress)
+	assert.False(t, isUnavailable(err))
+}
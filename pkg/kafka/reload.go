@@ -0,0 +1,272 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/IBM/sarama"
+	"go.uber.org/zap"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+// reloadKind tells how a config change is applied to the running source, from the least to the most disruptive.
+type reloadKind int
+
+const (
+	// nothing changed
+	reloadNone reloadKind = iota
+	// the clients and the consumer group are rebuilt with the new brokers, TLS settings or credentials
+	reloadClients
+	// the consumer group session is restarted once the messages in flight are acked
+	reloadSession
+	// the change can't be applied to a running source
+	reloadRefused
+)
+
+func (r reloadKind) String() string {
+	switch r {
+	case reloadNone:
+		return "none"
+	case reloadClients:
+		return "rebuild clients"
+	case reloadSession:
+		return "restart consumer"
+	default:
+		return "refused"
+	}
+}
+
+// secretsField is the name the changes of the content of the mounted secrets are reported with.
+const secretsField = "secrets"
+
+// reloadKinds are how the changes of the top level fields of the config are applied. The changes of the fields that
+// are not listed are refused.
+var reloadKinds = map[string]reloadKind{
+	"brokers": reloadClients,
+	"tls":     reloadClients,
+	"sasl":    reloadClients,
+	"topic":   reloadSession,
+	// the sarama options, such as the rebalance strategy and the initial offset
+	"config": reloadSession,
+}
+
+// identityFields identify the consumer of the topic, so changing them would commit the offsets to another group or stop
+// committing them at all.
+var identityFields = map[string]bool{
+	"consumerGroup": true,
+	"snapshot":      true,
+}
+
+// configChange is how a config change is applied.
+type configChange struct {
+	kind reloadKind
+	// changed fields
+	fields []string
+	// why the change is refused, if it is
+	reasons []string
+}
+
+// classifyChange classifies the change of the config of a running source. secretsChanged tells that the content of
+// the mounted secrets changed, which needs the clients to be rebuilt as well.
+func classifyChange(running, loaded *config.Config, secretsChanged bool) configChange {
+	change := configChange{fields: config.Diff(running, loaded)}
+	if secretsChanged {
+		change.fields = append(change.fields, secretsField)
+	}
+	for _, field := range change.fields {
+		kind, ok := reloadKinds[field]
+		switch {
+		case field == secretsField:
+			kind = reloadClients
+		case identityFields[field]:
+			kind = reloadRefused
+			change.reasons = append(change.reasons, fmt.Sprintf("%s identifies the consumer of the topic", field))
+		case !ok:
+			kind = reloadRefused
+			change.reasons = append(change.reasons, fmt.Sprintf("%s is only read when the source starts", field))
+		}
+		if kind > change.kind {
+			change.kind = kind
+		}
+	}
+	return change
+}
+
+// pendingReload is a reload waiting for the consumer and the pending refresher to stop, so that their clients and
+// config can be swapped.
+type pendingReload struct {
+	config       *config.Config
+	saramaConfig *sarama.Config
+	client       sarama.Client
+	admin        sarama.ClusterAdmin
+	// closed once the reload is applied, or abandoned with err
+	done chan struct{}
+	err  error
+}
+
+func (r *pendingReload) finish(err error) {
+	r.err = err
+	close(r.done)
+}
+
+// errNotConsuming is the error of the reloads requested while the source doesn't run a consumer group.
+var errNotConsuming = errors.New("the source is not consuming")
+
+// Reload applies a config loaded again while the source runs. secretsChanged tells that the content of the mounted
+// secrets changed. The brokers, TLS and SASL settings and the secrets are applied by rebuilding the clients and the
+// consumer group with the new settings. The topic and the sarama options are applied by restarting the consumer once
+// the messages in flight are acked, or once the shutdown timeout is over. Identity changes, such as a different consumer
+// group, and the changes of the fields only read at startup are refused: the source keeps running with the previous
+// config and the change is only applied by a restart. The new clients are created before the running ones are stopped,
+// so that a change the brokers reject doesn't interrupt the source.
+func (k *kafkaSource) Reload(c *config.Config, secretsChanged bool) error {
+	k.reloading.Lock()
+	defer k.reloading.Unlock()
+	change := classifyChange(k.cfg, c, secretsChanged)
+	logger := k.logger.With(zap.Strings("fields", change.fields), zap.Stringer("reload", change.kind))
+	switch {
+	case change.kind == reloadNone:
+		return nil
+	case change.kind == reloadRefused:
+		return fmt.Errorf("config change of %v refused, %s. Restart the source to apply it", change.fields, strings.Join(change.reasons, ", "))
+	case k.snapshot != nil:
+		return fmt.Errorf("config change of %v refused, the config can't be reloaded in snapshot mode. Restart the source to apply it", change.fields)
+	}
+	saramaConfig, err := NewSaramaConfig(c, k.volumeReader)
+	if err != nil {
+		return fmt.Errorf("config change of %v not applied, %w", change.fields, err)
+	}
+	client, err := sarama.NewClient(c.Brokers, saramaConfig)
+	if err != nil {
+		return fmt.Errorf("config change of %v not applied, failed to create sarama client, %w", change.fields, err)
+	}
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return fmt.Errorf("config change of %v not applied, failed to create sarama cluster admin client, %w", change.fields, err)
+	}
+
+	// no more messages are read until the consumer is restarted. The claims are released by the stop of the consumer
+	// once the message channel is full, after the drain, so that the acks of the drain are still committed.
+	k.paused.Store(true)
+	defer k.paused.Store(false)
+	if change.kind == reloadSession {
+		logger.Info("Restarting the consumer to apply a config change, waiting for inflight acks...", zap.Int64("inflight", k.inflight.Load()))
+		drainCtx, cancel := context.WithTimeout(k.lifecycleCtx, k.shutdownTimeout)
+		err := k.awaitAcks(drainCtx)
+		cancel()
+		if err != nil {
+			logger.Warn("Inflight messages not acked before the shutdown timeout, they will be read again", zap.Int64("inflight", k.inflight.Load()))
+		}
+	} else {
+		logger.Info("Rebuilding the kafka clients to apply a config change")
+	}
+	r := &pendingReload{config: c, saramaConfig: saramaConfig, client: client, admin: admin, done: make(chan struct{})}
+	if !k.requestReload(r) {
+		_ = admin.Close()
+		return fmt.Errorf("config change of %v not applied, %w", change.fields, errNotConsuming)
+	}
+	<-r.done
+	if r.err != nil {
+		return fmt.Errorf("config change of %v not applied, %w", change.fields, r.err)
+	}
+	logger.Info("Config change applied")
+	return nil
+}
+
+// requestReload hands a reload to the run loop and stops the consumer and the pending refresher. It returns false if
+// the run loop is not running.
+func (k *kafkaSource) requestReload(r *pendingReload) bool {
+	k.workersLock.Lock()
+	defer k.workersLock.Unlock()
+	if k.stopWorkers == nil {
+		return false
+	}
+	k.reload = r
+	k.stopWorkers()
+	return true
+}
+
+// run consumes the topic and refreshes the pending messages until the source is closed. A reload stops both, swaps
+// their clients and config, and starts them again.
+func (k *kafkaSource) run() {
+	defer close(k.stopCh)
+	for {
+		ctx, cancel := context.WithCancel(k.lifecycleCtx)
+		k.workersLock.Lock()
+		k.stopWorkers = cancel
+		k.workersLock.Unlock()
+
+		wg := new(sync.WaitGroup)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			k.runPendingRefresher(ctx)
+		}()
+		k.startConsumer(ctx)
+		cancel()
+		wg.Wait()
+
+		k.workersLock.Lock()
+		r := k.reload
+		k.reload = nil
+		if r == nil || k.lifecycleCtx.Err() != nil {
+			k.stopWorkers = nil
+		}
+		k.workersLock.Unlock()
+		if r == nil {
+			return
+		}
+		if k.lifecycleCtx.Err() != nil {
+			_ = r.admin.Close()
+			r.finish(errNotConsuming)
+			return
+		}
+		k.applyReload(r)
+		r.finish(nil)
+	}
+}
+
+// applyReload swaps the clients and the config of the source while the consumer and the pending refresher are stopped.
+// The state kept per partition is reset if the topic changed, as the partitions are no longer the same.
+func (k *kafkaSource) applyReload(r *pendingReload) {
+	// waits for the reads and acks in progress.
+	k.reloadLock.Lock()
+	defer k.reloadLock.Unlock()
+	if k.adminClient != nil {
+		// closes the underlying sarama client as well.
+		_ = k.adminClient.Close()
+	}
+	k.saramaClient, k.adminClient = r.client, r.admin
+	k.config = r.saramaConfig
+	k.brokers = r.config.Brokers
+	k.cfg = r.config
+	if r.config.Topic != k.topic {
+		k.logger.Info("Switching topic", zap.String("from", k.topic), zap.String("to", r.config.Topic))
+		k.topic = r.config.Topic
+		k.resetPartitionState()
+	}
+}
+
+// resetPartitionState drops the records buffered from the previous topic and the state kept for its partitions.
+func (k *kafkaSource) resetPartitionState() {
+	for len(k.handler.messages) > 0 {
+		<-k.handler.messages
+	}
+	k.backlog = nil
+	k.backlogSize.Store(0)
+	k.tracker = newOffsetTracker()
+	k.epochs = newLeaderEpochs(k.currentLeaderEpoch, k.logEndOffset)
+	k.pendingCache.set(nil)
+	// the stages are only configured at startup, they are created again to drop their state.
+	if err := k.initStages(k.cfg); err != nil {
+		k.logger.Error("Failed to reset the stages", zap.Error(err))
+	}
+	if k.tracer != nil {
+		k.tracer = newRecordTracer(k.tracerProvider, k.topic, k.consumerGrpName, k.tracer.batch)
+	}
+}
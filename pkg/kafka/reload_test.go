@@ -0,0 +1,283 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	sourcesdk "github.com/numaproj/numaflow-go/pkg/sourcer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+	"github.com/numaproj-contrib/kafka-source-go/pkg/utils"
+)
+
+func reloadConfig() *config.Config {
+	return &config.Config{
+		Brokers:           []string{"broker-1:9092"},
+		Topic:             "test-topic",
+		ConsumerGroupName: "test-group",
+	}
+}
+
+func TestClassifyChange(t *testing.T) {
+	tests := []struct {
+		name           string
+		change         func(c *config.Config)
+		secretsChanged bool
+		kind           reloadKind
+		fields         []string
+		reasons        []string
+	}{
+		{name: "unchanged", change: func(c *config.Config) {}, kind: reloadNone},
+		{name: "brokers", change: func(c *config.Config) {
+			c.Brokers = []string{"broker-2:9092"}
+		}, kind: reloadClients, fields: []string{"brokers"}},
+		{name: "credentials", change: func(c *config.Config) {
+			c.TLS = &config.TLS{CACertSecret: &corev1.SecretKeySelector{Key: "ca.crt"}}
+		}, kind: reloadClients, fields: []string{"tls"}},
+		{name: "secrets content", change: func(c *config.Config) {}, secretsChanged: true,
+			kind: reloadClients, fields: []string{secretsField}},
+		{name: "topic", change: func(c *config.Config) {
+			c.Topic = "other-topic"
+		}, kind: reloadSession, fields: []string{"topic"}},
+		{name: "rebalance strategy", change: func(c *config.Config) {
+			c.Brokers = []string{"broker-2:9092"}
+			c.Config = "consumer:\n  group:\n    rebalance:\n      strategy: roundrobin\n"
+		}, kind: reloadSession, fields: []string{"brokers", "config"}},
+		{name: "consumer group", change: func(c *config.Config) {
+			c.Topic = "other-topic"
+			c.ConsumerGroupName = "other-group"
+		}, kind: reloadRefused, fields: []string{"topic", "consumerGroup"},
+			reasons: []string{"consumerGroup identifies the consumer of the topic"}},
+		{name: "startup only", change: func(c *config.Config) {
+			c.Dedup = &config.Dedup{By: config.DedupByKey}
+		}, kind: reloadRefused, fields: []string{"dedup"},
+			reasons: []string{"dedup is only read when the source starts"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loaded := reloadConfig()
+			tt.change(loaded)
+			change := classifyChange(reloadConfig(), loaded, tt.secretsChanged)
+			assert.Equal(t, tt.kind, change.kind)
+			assert.ElementsMatch(t, tt.fields, change.fields)
+			assert.Equal(t, tt.reasons, change.reasons)
+		})
+	}
+}
+
+func TestReload_Refused(t *testing.T) {
+	k := &kafkaSource{cfg: reloadConfig(), logger: zap.NewNop()}
+	loaded := reloadConfig()
+	loaded.ConsumerGroupName = "other-group"
+	err := k.Reload(loaded, false)
+	assert.EqualError(t, err, "config change of [consumerGroup] refused, consumerGroup identifies the consumer of the topic. Restart the source to apply it")
+	assert.Equal(t, "test-group", k.cfg.ConsumerGroupName)
+
+	// nothing changed
+	assert.NoError(t, k.Reload(reloadConfig(), false))
+
+	k.snapshot = &config.Snapshot{}
+	loaded = reloadConfig()
+	loaded.Topic = "other-topic"
+	assert.ErrorContains(t, k.Reload(loaded, false), "can't be reloaded in snapshot mode")
+}
+
+func TestReload_NotConsuming(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetController(broker.BrokerID()),
+	})
+
+	k := &kafkaSource{
+		cfg:          reloadConfig(),
+		volumeReader: utils.NewKafkaVolumeReader(t.TempDir()),
+		logger:       zap.NewNop(),
+	}
+	loaded := reloadConfig()
+	loaded.Brokers = []string{broker.Addr()}
+	err := k.Reload(loaded, false)
+	assert.ErrorIs(t, err, errNotConsuming)
+	assert.False(t, k.paused.Load())
+	assert.Equal(t, reloadConfig().Brokers, k.cfg.Brokers)
+
+	// the new clients are checked before the consumer is stopped
+	loaded.Brokers = []string{"127.0.0.1:1"}
+	assert.ErrorContains(t, k.Reload(loaded, false), "failed to create sarama client")
+}
+
+func TestRequestReload(t *testing.T) {
+	k := &kafkaSource{}
+	r := &pendingReload{done: make(chan struct{})}
+	assert.False(t, k.requestReload(r))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	k.stopWorkers = cancel
+	assert.True(t, k.requestReload(r))
+	assert.Error(t, ctx.Err())
+	assert.Same(t, r, k.reload)
+}
+
+func newReloadClients(t *testing.T, topic string) (*sarama.MockBroker, sarama.Client, sarama.ClusterAdmin) {
+	broker := sarama.NewMockBroker(t, 1)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetController(broker.BrokerID()).
+			SetLeader(topic, 0, broker.BrokerID()),
+	})
+	client, err := sarama.NewClient([]string{broker.Addr()}, sarama.NewConfig())
+	require.NoError(t, err)
+	admin, err := sarama.NewClusterAdminFromClient(client)
+	require.NoError(t, err)
+	return broker, client, admin
+}
+
+func TestApplyReload(t *testing.T) {
+	oldBroker, oldClient, oldAdmin := newReloadClients(t, "test-topic")
+	defer oldBroker.Close()
+	newBroker, newClient, newAdmin := newReloadClients(t, "other-topic")
+	defer newBroker.Close()
+
+	k := &kafkaSource{
+		cfg:          reloadConfig(),
+		topic:        "test-topic",
+		brokers:      []string{oldBroker.Addr()},
+		saramaClient: oldClient,
+		adminClient:  oldAdmin,
+		handler:      newConsumerHandler(2),
+		tracker:      newOffsetTracker(),
+		pendingCache: &pendingCache{},
+		logger:       zap.NewNop(),
+	}
+	k.tracker.track("test-topic", 0, 10)
+	k.handler.messages <- &sarama.ConsumerMessage{Topic: "test-topic", Offset: 11}
+	k.backlog = []sourcesdk.Message{{}}
+	k.pendingCache.set(&lagReport{total: 5})
+
+	loaded := reloadConfig()
+	loaded.Brokers = []string{newBroker.Addr()}
+	// the same topic keeps the state of its partitions
+	k.applyReload(&pendingReload{config: loaded, saramaConfig: sarama.NewConfig(), client: newClient, admin: newAdmin})
+	assert.True(t, oldClient.Closed())
+	assert.Same(t, newClient, k.saramaClient)
+	assert.Equal(t, []string{newBroker.Addr()}, k.brokers)
+	assert.Same(t, loaded, k.cfg)
+	assert.Len(t, k.handler.messages, 1)
+	assert.Len(t, k.backlog, 1)
+	assert.NotNil(t, k.pendingCache.get())
+
+	switched := reloadConfig()
+	switched.Brokers = loaded.Brokers
+	switched.Topic = "other-topic"
+	k.applyReload(&pendingReload{config: switched, saramaConfig: sarama.NewConfig(), client: newClient, admin: newAdmin})
+	defer k.adminClient.Close()
+	assert.Equal(t, "other-topic", k.topic)
+	assert.Len(t, k.handler.messages, 0)
+	assert.Nil(t, k.backlog)
+	assert.Nil(t, k.pendingCache.get())
+	_, tracked := k.tracker.ack("test-topic", 0, 10)
+	assert.False(t, tracked)
+}
+
+func TestReload_FullBuffer(t *testing.T) {
+	oldBroker, oldClient, oldAdmin := newReloadClients(t, "test-topic")
+	defer oldBroker.Close()
+	newBroker, _, _ := newReloadClients(t, "other-topic")
+	defer newBroker.Close()
+
+	lifecycleCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	k := &kafkaSource{
+		cfg:             reloadConfig(),
+		topic:           "test-topic",
+		saramaClient:    oldClient,
+		adminClient:     oldAdmin,
+		handler:         newConsumerHandler(1),
+		tracker:         newOffsetTracker(),
+		pendingCache:    &pendingCache{},
+		volumeReader:    utils.NewKafkaVolumeReader(t.TempDir()),
+		shutdownTimeout: 100 * time.Millisecond,
+		lifecycleCtx:    lifecycleCtx,
+		logger:          zap.NewNop(),
+	}
+	defer func() { _ = k.adminClient.Close() }()
+	// a message in flight that is never acked, and a claim blocked on the full buffer
+	k.inflight.Add(1)
+	k.handler.messages <- &sarama.ConsumerMessage{Topic: "test-topic", Offset: 0}
+	claim := channelClaim{messages: make(chan *sarama.ConsumerMessage, 1)}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "test-topic", Offset: 1}
+	ctx, cancel := context.WithCancel(lifecycleCtx)
+	k.stopWorkers = cancel
+	// the run loop, applying the reload once the consumer is stopped
+	go func() {
+		_ = k.handler.ConsumeClaim(claimSession{ctx: ctx}, claim)
+		k.workersLock.Lock()
+		r := k.reload
+		k.reload, k.stopWorkers = nil, nil
+		k.workersLock.Unlock()
+		k.applyReload(r)
+		r.finish(nil)
+	}()
+
+	loaded := reloadConfig()
+	loaded.Brokers = []string{newBroker.Addr()}
+	loaded.Topic = "other-topic"
+	reloaded := make(chan error, 1)
+	go func() {
+		reloaded <- k.Reload(loaded, false)
+	}()
+	select {
+	case err := <-reloaded:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the reload didn't complete with a full buffer")
+	}
+	assert.Equal(t, "other-topic", k.topic)
+	assert.False(t, k.paused.Load())
+	assert.Len(t, k.handler.messages, 0)
+}
+
+func TestAck_StaleTopicAfterReload(t *testing.T) {
+	oldBroker, oldClient, oldAdmin := newReloadClients(t, "test-topic")
+	defer oldBroker.Close()
+	newBroker, newClient, newAdmin := newReloadClients(t, "other-topic")
+	defer newBroker.Close()
+
+	sess := &markSession{}
+	k := &kafkaSource{
+		cfg:          reloadConfig(),
+		topic:        "test-topic",
+		saramaClient: oldClient,
+		adminClient:  oldAdmin,
+		handler:      newConsumerHandler(1),
+		tracker:      newOffsetTracker(),
+		pendingCache: &pendingCache{},
+		logger:       zap.NewNop(),
+	}
+	k.handler.sess = sess
+	k.tracker.track("test-topic", 0, 10)
+	stale := GenerateSourceSdkOffset(&sarama.ConsumerMessage{Topic: "test-topic", Partition: 0, Offset: 10}, unknownLeaderEpoch)
+
+	switched := reloadConfig()
+	switched.Brokers = []string{newBroker.Addr()}
+	switched.Topic = "other-topic"
+	k.applyReload(&pendingReload{config: switched, saramaConfig: sarama.NewConfig(), client: newClient, admin: newAdmin})
+	defer k.adminClient.Close()
+	for o := int64(9); o <= 10; o++ {
+		k.tracker.track("other-topic", 0, o)
+	}
+	// the ack of the previous topic doesn't commit the records of the new one
+	k.Ack(context.Background(), ackRequest{stale})
+	assert.Empty(t, sess.marked)
+	k.Ack(context.Background(), ackRequest{GenerateSourceSdkOffset(&sarama.ConsumerMessage{Topic: "other-topic", Partition: 0, Offset: 9}, unknownLeaderEpoch)})
+	assert.Equal(t, []int64{9}, sess.marked)
+}
@@ -0,0 +1,65 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultShutdownTimeout = 30 * time.Second
+	// how often the inflight acks are checked while draining
+	drainPollInterval = 100 * time.Millisecond
+)
+
+// Shutdown stops reading, waits up to the shutdown timeout for the acks of the messages in flight, commits the acked
+// offsets and leaves the consumer group. The messages that are not acked by then are read again after the restart.
+// ctx bounds the whole shutdown, including the close of the clients.
+func (k *kafkaSource) Shutdown(ctx context.Context) error {
+	k.draining.Store(true)
+	k.logger.Info("Shutting down kafka reader, waiting for inflight acks...", zap.Int64("inflight", k.inflight.Load()))
+	drainCtx, cancel := context.WithTimeout(ctx, k.shutdownTimeout)
+	defer cancel()
+	if err := k.awaitAcks(drainCtx); err != nil {
+		k.logger.Warn("Inflight messages not acked before the shutdown deadline, they will be read again", zap.Int64("inflight", k.inflight.Load()))
+	}
+	if sess := k.handler.sess; sess != nil && k.snapshot == nil {
+		// commits the marked offsets synchronously.
+		sess.Commit()
+		k.logger.Info("Acked offsets committed")
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		// the consumer group leaves the group once its session is over.
+		_ = k.Close()
+	}()
+	select {
+	case <-closed:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("kafka reader not closed before the shutdown deadline, %w", ctx.Err())
+	}
+}
+
+// ShutdownTimeout returns how long Shutdown waits for the acks of the messages in flight.
+func (k *kafkaSource) ShutdownTimeout() time.Duration {
+	return k.shutdownTimeout
+}
+
+// awaitAcks waits until all the messages sent to the message channel are acked.
+func (k *kafkaSource) awaitAcks(ctx context.Context) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for k.inflight.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
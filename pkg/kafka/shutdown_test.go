@@ -0,0 +1,114 @@
+package kafka
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	sourcesdk "github.com/numaproj/numaflow-go/pkg/sourcer"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type commitSession struct {
+	sarama.ConsumerGroupSession
+	commits atomic.Int32
+}
+
+func (s *commitSession) Commit() {
+	s.commits.Add(1)
+}
+
+type readRequest struct {
+	count   uint64
+	timeout time.Duration
+}
+
+func (r readRequest) Count() uint64          { return r.count }
+func (r readRequest) TimeOut() time.Duration { return r.timeout }
+
+func newShutdownSource(timeout time.Duration) (*kafkaSource, *commitSession) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sess := &commitSession{}
+	k := &kafkaSource{
+		handler:         newConsumerHandler(1),
+		shutdownTimeout: timeout,
+		lifecycleCtx:    ctx,
+		cancelFn:        cancel,
+		stopCh:          make(chan struct{}),
+		logger:          zap.NewNop(),
+	}
+	k.handler.sess = sess
+	go func() {
+		<-ctx.Done()
+		close(k.stopCh)
+	}()
+	return k, sess
+}
+
+func TestShutdown_WaitsForInflightAcks(t *testing.T) {
+	k, sess := newShutdownSource(time.Minute)
+	k.inflight.Add(2)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		k.inflight.Add(-2)
+	}()
+	start := time.Now()
+	assert.NoError(t, k.Shutdown(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	assert.Equal(t, int32(1), sess.commits.Load())
+	assert.Error(t, k.lifecycleCtx.Err())
+
+	// no more messages are read
+	k.handler.messages <- &sarama.ConsumerMessage{Topic: "test-topic"}
+	messageCh := make(chan sourcesdk.Message, 1)
+	k.Read(context.Background(), readRequest{count: 1, timeout: time.Second}, messageCh)
+	assert.Empty(t, messageCh)
+}
+
+func TestShutdown_DeadlineExceeded(t *testing.T) {
+	k, sess := newShutdownSource(200 * time.Millisecond)
+	k.inflight.Add(1)
+	// the acked offsets are committed even though some messages are not acked
+	assert.NoError(t, k.Shutdown(context.Background()))
+	assert.Equal(t, int32(1), sess.commits.Load())
+	assert.Equal(t, int64(1), k.inflight.Load())
+}
+
+type claimSession struct {
+	sarama.ConsumerGroupSession
+	ctx context.Context
+}
+
+func (s claimSession) Context() context.Context { return s.ctx }
+
+type channelClaim struct {
+	sarama.ConsumerGroupClaim
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c channelClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+func TestConsumeClaim_EndsWithFullBuffer(t *testing.T) {
+	h := newConsumerHandler(1)
+	claim := channelClaim{messages: make(chan *sarama.ConsumerMessage, 2)}
+	claim.messages <- &sarama.ConsumerMessage{Offset: 0}
+	claim.messages <- &sarama.ConsumerMessage{Offset: 1}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- h.ConsumeClaim(claimSession{ctx: ctx}, claim)
+	}()
+	// nothing reads the buffer while the source drains
+	assert.Eventually(t, func() bool { return len(claim.messages) == 0 }, time.Second, 10*time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("the claim was not released at the end of the session")
+	}
+	assert.Len(t, h.messages, 1)
+}
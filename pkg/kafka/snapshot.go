@@ -0,0 +1,283 @@
+package kafka
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"go.uber.org/zap"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/metrics"
+)
+
+// snapshotPartition is a partition consumer positioned right after the snapshot.
+type snapshotPartition struct {
+	sarama.PartitionConsumer
+	// first record read past the end of the snapshot, the first one streamed.
+	next *sarama.ConsumerMessage
+}
+
+// snapshot accumulates the latest record of every key read from a compacted topic.
+type snapshot struct {
+	latest map[string]*sarama.ConsumerMessage
+	// number of records without a key, they can't be compacted and are left out of the snapshot.
+	keyless int
+}
+
+func newSnapshot() *snapshot {
+	return &snapshot{
+		latest: make(map[string]*sarama.ConsumerMessage),
+	}
+}
+
+// add records m as the latest value of its key, a tombstone (nil value) removes the key from the snapshot.
+func (s *snapshot) add(m *sarama.ConsumerMessage) {
+	if m.Key == nil {
+		s.keyless++
+		return
+	}
+	if m.Value == nil {
+		delete(s.latest, string(m.Key))
+		return
+	}
+	s.latest[string(m.Key)] = m
+}
+
+// records returns the snapshot ordered by partition and offset, which is the order the records were produced in.
+func (s *snapshot) records() []*sarama.ConsumerMessage {
+	result := make([]*sarama.ConsumerMessage, 0, len(s.latest))
+	for _, m := range s.latest {
+		result = append(result, m)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Partition != result[j].Partition {
+			return result[i].Partition < result[j].Partition
+		}
+		return result[i].Offset < result[j].Offset
+	})
+	return result
+}
+
+// startSnapshot builds the snapshot of the topic and hands it to the consumer handler.
+// If streaming is enabled, it keeps forwarding the records that arrive after the snapshot until the source is closed.
+func (k *kafkaSource) startSnapshot() {
+	defer close(k.stopCh)
+	k.health.consumeStarted()
+	defer k.health.consumeReturned()
+	sup := newSupervisor(k.retry, k.logger)
+	var consumer sarama.Consumer
+	var pcs []*snapshotPartition
+	for {
+		var err error
+		if consumer, pcs, err = k.openSnapshot(); err == nil {
+			break
+		}
+		metrics.ConsumerErrorsTotal.WithLabelValues(k.topic).Inc()
+		k.health.recordError(err)
+		if rErr := sup.retry(k.lifecycleCtx, "read the topic snapshot", err); rErr != nil {
+			if k.lifecycleCtx.Err() != nil {
+				return
+			}
+			k.fail(fmt.Errorf("giving up reading the topic snapshot, %w", rErr))
+			return
+		}
+	}
+	defer func() {
+		_ = consumer.Close()
+	}()
+	if pcs == nil {
+		// the source was closed while reading the snapshot.
+		return
+	}
+	if !k.snapshot.Stream {
+		for _, pc := range pcs {
+			pc.AsyncClose()
+		}
+		<-k.lifecycleCtx.Done()
+		return
+	}
+
+	k.logger.Info("Snapshot emitted, streaming incremental updates", zap.String("topic", k.topic))
+	wg := new(sync.WaitGroup)
+	for _, pc := range pcs {
+		wg.Add(1)
+		go func(pc *snapshotPartition) {
+			defer wg.Done()
+			defer pc.AsyncClose()
+			if pc.next != nil {
+				select {
+				case k.handler.messages <- pc.next:
+				case <-k.lifecycleCtx.Done():
+					return
+				}
+			}
+			for {
+				select {
+				case <-k.lifecycleCtx.Done():
+					return
+				case cErr := <-pc.Errors():
+					metrics.ConsumerErrorsTotal.WithLabelValues(k.topic).Inc()
+					k.health.recordError(cErr)
+					k.logger.Error("Kafka partition consumer error", zap.Error(cErr))
+				case msg := <-pc.Messages():
+					select {
+					case k.handler.messages <- msg:
+					case <-k.lifecycleCtx.Done():
+						return
+					}
+				}
+			}
+		}(pc)
+	}
+	wg.Wait()
+}
+
+// openSnapshot creates a consumer and reads the snapshot with it. The consumer is closed if reading fails, so that the
+// next attempt starts over.
+func (k *kafkaSource) openSnapshot() (sarama.Consumer, []*snapshotPartition, error) {
+	consumer, err := sarama.NewConsumerFromClient(k.saramaClient)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create sarama consumer for the snapshot, %w", err)
+	}
+	pcs, err := k.readSnapshot(consumer)
+	if err != nil {
+		_ = consumer.Close()
+		return nil, nil, err
+	}
+	return consumer, pcs, nil
+}
+
+// readSnapshot reads every partition up to the end offset captured when it starts and sends the latest record of every
+// key to the consumer handler. It returns the partition consumers positioned right after the snapshot, or nil if the
+// source was closed in the meantime.
+func (k *kafkaSource) readSnapshot(consumer sarama.Consumer) ([]*snapshotPartition, error) {
+	partitions, err := k.saramaClient.Partitions(k.topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions of topic %s, %w", k.topic, err)
+	}
+	ends := make(map[int32]int64, len(partitions))
+	for _, partition := range partitions {
+		end, err := k.saramaClient.GetOffset(k.topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the end offset of partition %d, %w", partition, err)
+		}
+		ends[partition] = end
+	}
+	k.logger.Info("Reading topic snapshot", zap.String("topic", k.topic), zap.Any("endOffsets", ends))
+	// there is no consumer group in snapshot mode, the source reads all the partitions.
+	k.health.sessionStarted(len(partitions))
+
+	s := newSnapshot()
+	pcs := make([]*snapshotPartition, 0, len(partitions))
+	closeAll := func() {
+		for _, pc := range pcs {
+			pc.AsyncClose()
+		}
+	}
+	for _, partition := range partitions {
+		start := sarama.OffsetOldest
+		oldest, err := k.saramaClient.GetOffset(k.topic, partition, sarama.OffsetOldest)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("failed to get the oldest offset of partition %d, %w", partition, err)
+		}
+		if oldest >= ends[partition] {
+			// nothing to read, start streaming from the captured end offset.
+			start = ends[partition]
+		}
+		pc, err := consumer.ConsumePartition(k.topic, partition, start)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("failed to consume partition %d, %w", partition, err)
+		}
+		p := &snapshotPartition{PartitionConsumer: pc}
+		pcs = append(pcs, p)
+		if oldest >= ends[partition] {
+			continue
+		}
+		if p.next, err = k.readPartition(s, pc, partition, oldest, ends[partition]); err != nil {
+			closeAll()
+			return nil, nil
+		}
+	}
+	if s.keyless > 0 {
+		k.logger.Warn("Skipped records without a key while building the snapshot", zap.Int("count", s.keyless))
+	}
+
+	records := s.records()
+	k.logger.Info("Topic snapshot read", zap.String("topic", k.topic), zap.Int("keys", len(records)))
+	for _, m := range records {
+		select {
+		case k.handler.messages <- m:
+		case <-k.lifecycleCtx.Done():
+			closeAll()
+			return nil, nil
+		}
+	}
+	return pcs, nil
+}
+
+// readPartition adds the records of a partition from the offset next up to its end offset to the snapshot. The last
+// offsets before the end may hold no record the consumer delivers, such as the markers of transactions. So the partition
+// is read up to the end once a record past the end is read, which is returned to be streamed, or once no record arrived
+// for a whole fetch wait and the broker holds no record to deliver before the end. It returns an error if the source was
+// closed in the meantime.
+func (k *kafkaSource) readPartition(s *snapshot, pc sarama.PartitionConsumer, partition int32, next, end int64) (*sarama.ConsumerMessage, error) {
+	ticker := time.NewTicker(k.config.Consumer.MaxWaitTime)
+	defer ticker.Stop()
+	idle := false
+	for {
+		select {
+		case <-k.lifecycleCtx.Done():
+			return nil, k.lifecycleCtx.Err()
+		case cErr := <-pc.Errors():
+			metrics.ConsumerErrorsTotal.WithLabelValues(k.topic).Inc()
+			k.health.recordError(cErr)
+			k.logger.Error("Kafka partition consumer error", zap.Error(cErr))
+		case msg := <-pc.Messages():
+			idle = false
+			if msg.Offset >= end {
+				return msg, nil
+			}
+			k.health.recordRead()
+			s.add(msg)
+			if next = msg.Offset + 1; next >= end {
+				return nil, nil
+			}
+		case <-ticker.C:
+			if !idle {
+				idle = true
+				continue
+			}
+			deliverable, err := k.deliverableBefore(partition, next, end)
+			if err != nil {
+				k.logger.Warn("Failed to check the end of the partition snapshot", zap.Int32("partition", partition), zap.Error(err))
+				continue
+			}
+			if !deliverable {
+				return nil, nil
+			}
+		}
+	}
+}
+
+// deliverableBefore tells whether a partition holds a record the consumer delivers from the offset up to the end offset.
+// The offsets holding no such record are the markers of transactions and, when reading committed records only, the
+// records of aborted transactions.
+func (k *kafkaSource) deliverableBefore(partition int32, offset, end int64) (bool, error) {
+	if !k.config.Version.IsAtLeast(sarama.V0_11_0_0) {
+		// there are no transactions before kafka 0.11, the offsets up to the end all hold a record.
+		return true, nil
+	}
+	records, partial, err := fetchRecords(k.saramaClient, k.config, k.topic, partition, offset)
+	if err != nil {
+		return false, err
+	}
+	if partial {
+		// a record too large for the fetch, the consumer delivers it.
+		return true, nil
+	}
+	return len(records) > 0 && records[0].offset < end, nil
+}
@@ -0,0 +1,104 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSnapshot_LatestValuePerKey(t *testing.T) {
+	s := newSnapshot()
+	s.add(&sarama.ConsumerMessage{Key: []byte("a"), Value: []byte("a1"), Partition: 0, Offset: 0})
+	s.add(&sarama.ConsumerMessage{Key: []byte("b"), Value: []byte("b1"), Partition: 1, Offset: 0})
+	s.add(&sarama.ConsumerMessage{Key: []byte("a"), Value: []byte("a2"), Partition: 0, Offset: 1})
+	s.add(&sarama.ConsumerMessage{Key: []byte("c"), Value: []byte("c1"), Partition: 0, Offset: 2})
+	// tombstone
+	s.add(&sarama.ConsumerMessage{Key: []byte("b"), Value: nil, Partition: 1, Offset: 1})
+	// keyless records are skipped
+	s.add(&sarama.ConsumerMessage{Key: nil, Value: []byte("x"), Partition: 0, Offset: 3})
+
+	records := s.records()
+	assert.Len(t, records, 2)
+	assert.Equal(t, "a2", string(records[0].Value))
+	assert.Equal(t, int64(1), records[0].Offset)
+	assert.Equal(t, "c1", string(records[1].Value))
+	assert.Equal(t, 1, s.keyless)
+}
+
+func TestSnapshot_OrderedByPartitionAndOffset(t *testing.T) {
+	s := newSnapshot()
+	s.add(&sarama.ConsumerMessage{Key: []byte("z"), Value: []byte("1"), Partition: 1, Offset: 5})
+	s.add(&sarama.ConsumerMessage{Key: []byte("y"), Value: []byte("2"), Partition: 0, Offset: 9})
+	s.add(&sarama.ConsumerMessage{Key: []byte("x"), Value: []byte("3"), Partition: 0, Offset: 4})
+
+	records := s.records()
+	assert.Len(t, records, 3)
+	assert.Equal(t, []string{"x", "y", "z"}, []string{string(records[0].Key), string(records[1].Key), string(records[2].Key)})
+}
+
+func TestReadSnapshot_GapAtTheEnd(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("test-topic", 0, broker.BrokerID()).
+			SetLeader("test-topic", 1, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("test-topic", 0, sarama.OffsetOldest, 0).
+			SetOffset("test-topic", 0, sarama.OffsetNewest, 3).
+			SetOffset("test-topic", 1, sarama.OffsetOldest, 0).
+			SetOffset("test-topic", 1, sarama.OffsetNewest, 3),
+		// offset 2 of both partitions is a transaction marker, partition 1 got a record past the end of the snapshot.
+		"FetchRequest": sarama.NewMockFetchResponse(t, 10).
+			SetMessageWithKey("test-topic", 0, 0, sarama.StringEncoder("a"), sarama.StringEncoder("a1")).
+			SetMessageWithKey("test-topic", 0, 1, sarama.StringEncoder("b"), sarama.StringEncoder("b1")).
+			SetHighWaterMark("test-topic", 0, 3).
+			SetMessageWithKey("test-topic", 1, 0, sarama.StringEncoder("c"), sarama.StringEncoder("c1")).
+			SetMessageWithKey("test-topic", 1, 1, sarama.StringEncoder("c"), sarama.StringEncoder("c2")).
+			SetMessageWithKey("test-topic", 1, 3, sarama.StringEncoder("c"), sarama.StringEncoder("c3")).
+			SetHighWaterMark("test-topic", 1, 4),
+	})
+
+	config := sarama.NewConfig()
+	config.Consumer.MaxWaitTime = 20 * time.Millisecond
+	client, err := sarama.NewClient([]string{broker.Addr()}, config)
+	require.NoError(t, err)
+	defer client.Close()
+	consumer, err := sarama.NewConsumerFromClient(client)
+	require.NoError(t, err)
+	defer consumer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	k := &kafkaSource{
+		topic:        "test-topic",
+		config:       config,
+		saramaClient: client,
+		handler:      newConsumerHandler(10),
+		health:       newConsumerHealth(nil),
+		lifecycleCtx: ctx,
+		logger:       zap.NewNop(),
+	}
+	pcs, err := k.readSnapshot(consumer)
+	require.NoError(t, err)
+	require.Len(t, pcs, 2)
+	for _, pc := range pcs {
+		defer pc.AsyncClose()
+	}
+
+	var values []string
+	for len(k.handler.messages) > 0 {
+		values = append(values, string((<-k.handler.messages).Value))
+	}
+	assert.Equal(t, []string{"a1", "b1", "c2"}, values)
+	assert.Nil(t, pcs[0].next)
+	require.NotNil(t, pcs[1].next)
+	// the record past the end is streamed, not emitted with the snapshot
+	assert.Equal(t, int64(3), pcs[1].next.Offset)
+}
@@ -0,0 +1,53 @@
+package kafka
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+// splitter explodes the value of a record batching several events into one value per event.
+type splitter struct {
+	split func(value []byte) ([][]byte, error)
+}
+
+func newSplitter(c *config.Split) (*splitter, error) {
+	switch c.Format {
+	case config.SplitFormatJSONArray:
+		return &splitter{split: splitJSONArray}, nil
+	case config.SplitFormatNDJSON:
+		return &splitter{split: splitNDJSON}, nil
+	default:
+		return nil, fmt.Errorf("failed to parse split format %q. Must be one of the following: ['%s', '%s']", c.Format, config.SplitFormatJSONArray, config.SplitFormatNDJSON)
+	}
+}
+
+func splitJSONArray(value []byte) ([][]byte, error) {
+	var elements []json.RawMessage
+	if err := json.Unmarshal(value, &elements); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON array, %w", err)
+	}
+	result := make([][]byte, 0, len(elements))
+	for _, e := range elements {
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+// splitNDJSON splits newline-delimited JSON, blank lines are skipped.
+func splitNDJSON(value []byte) ([][]byte, error) {
+	var result [][]byte
+	for i, line := range bytes.Split(value, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if !json.Valid(line) {
+			return nil, fmt.Errorf("invalid JSON on line %d", i+1)
+		}
+		result = append(result, line)
+	}
+	return result, nil
+}
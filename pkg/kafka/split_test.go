@@ -0,0 +1,38 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+func TestSplitter_JSONArray(t *testing.T) {
+	s, err := newSplitter(&config.Split{Format: config.SplitFormatJSONArray})
+	assert.NoError(t, err)
+	values, err := s.split([]byte(`[{"a":1}, 2, "three"]`))
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte(`{"a":1}`), []byte(`2`), []byte(`"three"`)}, values)
+
+	values, err = s.split([]byte(`[]`))
+	assert.NoError(t, err)
+	assert.Empty(t, values)
+
+	_, err = s.split([]byte(`{"a":1}`))
+	assert.Error(t, err)
+}
+
+func TestSplitter_NDJSON(t *testing.T) {
+	s, err := newSplitter(&config.Split{Format: config.SplitFormatNDJSON})
+	assert.NoError(t, err)
+	values, err := s.split([]byte("{\"a\":1}\n\n{\"b\":2}\r\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte(`{"a":1}`), []byte(`{"b":2}`)}, values)
+
+	_, err = s.split([]byte("{\"a\":1}\nnot json"))
+	assert.ErrorContains(t, err, "line 2")
+
+	_, err = newSplitter(&config.Split{Format: "csv"})
+	assert.Error(t, err)
+}
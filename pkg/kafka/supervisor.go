@@ -0,0 +1,141 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/IBM/sarama"
+	"go.uber.org/zap"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+const (
+	defaultMaxRetries     = 10
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = time.Minute
+	// how long an attempt has to stay up before its failure, for the retries before it to be forgotten
+	defaultHealthyPeriod = 5 * time.Minute
+)
+
+// fatalErrors are the errors retrying can't fix, they need a configuration or an ACL change.
+var fatalErrors = []error{
+	sarama.ErrInvalidTopic,
+	sarama.ErrInvalidGroupId,
+	sarama.ErrTopicAuthorizationFailed,
+	sarama.ErrGroupAuthorizationFailed,
+	sarama.ErrClusterAuthorizationFailed,
+	sarama.ErrUnsupportedSASLMechanism,
+	sarama.ErrIllegalSASLState,
+	sarama.ErrUnsupportedVersion,
+	sarama.ErrSASLAuthenticationFailed,
+}
+
+// isRetryable reports whether an error of the kafka clients may go away by retrying, e.g. while the brokers restart.
+func isRetryable(err error) bool {
+	var configErr sarama.ConfigurationError
+	if errors.As(err, &configErr) {
+		return false
+	}
+	for _, fatal := range fatalErrors {
+		if errors.Is(err, fatal) {
+			return false
+		}
+	}
+	return true
+}
+
+// supervisor retries the failed operations with a jittered exponential backoff, up to a number of consecutive retries.
+type supervisor struct {
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	healthyPeriod  time.Duration
+	logger         *zap.Logger
+
+	// consecutive retries since the last success
+	retries int
+	// returns a random number in [0, n)
+	jitter func(n int64) int64
+	sleep  func(ctx context.Context, d time.Duration) error
+}
+
+func newSupervisor(c *config.Retry, logger *zap.Logger) *supervisor {
+	s := &supervisor{
+		maxRetries:     defaultMaxRetries,
+		initialBackoff: defaultInitialBackoff,
+		maxBackoff:     defaultMaxBackoff,
+		healthyPeriod:  defaultHealthyPeriod,
+		logger:         logger,
+		jitter:         rand.Int63n,
+		sleep:          sleepContext,
+	}
+	if c != nil {
+		if c.MaxRetries != 0 {
+			s.maxRetries = c.MaxRetries
+		}
+		if c.InitialBackoff > 0 {
+			s.initialBackoff = c.InitialBackoff
+		}
+		if c.MaxBackoff > 0 {
+			s.maxBackoff = c.MaxBackoff
+		}
+	}
+	return s
+}
+
+// retry waits before the next attempt of an operation that failed with err. It returns an error if err is fatal, if
+// the retries are exhausted or if ctx is done.
+func (s *supervisor) retry(ctx context.Context, op string, err error) error {
+	if !isRetryable(err) {
+		return fmt.Errorf("failed to %s, %w", op, err)
+	}
+	if s.maxRetries >= 0 && s.retries >= s.maxRetries {
+		return fmt.Errorf("failed to %s after %d retries, %w", op, s.retries, err)
+	}
+	backoff := s.backoff()
+	s.retries++
+	s.logger.Warn("Retrying after a kafka failure", zap.String("operation", op), zap.Int("retry", s.retries), zap.Duration("backoff", backoff), zap.Error(err))
+	return s.sleep(ctx, backoff)
+}
+
+// succeeded resets the retries and the backoff after a successful attempt.
+func (s *supervisor) succeeded() {
+	s.retries = 0
+}
+
+// failedAfter resets the retries and the backoff when an attempt that failed stayed up for the healthy period, so that
+// the failures spread over a long run don't exhaust the retries.
+func (s *supervisor) failedAfter(up time.Duration) {
+	if up >= s.healthyPeriod {
+		s.succeeded()
+	}
+}
+
+// backoff returns the backoff before the next retry. It doubles on every retry up to the max backoff, and half of it
+// is random so that the replicas don't reconnect all at once.
+func (s *supervisor) backoff() time.Duration {
+	d := s.initialBackoff
+	for i := 0; i < s.retries && d < s.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > s.maxBackoff {
+		d = s.maxBackoff
+	}
+	half := int64(d / 2)
+	return time.Duration(half + s.jitter(half+1))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,122 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, isRetryable(sarama.ErrOutOfBrokers))
+	assert.True(t, isRetryable(sarama.ErrNotCoordinatorForConsumer))
+	assert.True(t, isRetryable(fmt.Errorf("failed to create sarama client, %w", sarama.ErrOutOfBrokers)))
+	assert.False(t, isRetryable(sarama.ErrGroupAuthorizationFailed))
+	assert.False(t, isRetryable(fmt.Errorf("kafka consumer failed, %w", sarama.ErrTopicAuthorizationFailed)))
+	assert.False(t, isRetryable(sarama.ConfigurationError("Consumer.Group.Rebalance.Timeout must be >= 1ms")))
+}
+
+func newTestSupervisor(c *config.Retry) (*supervisor, *[]time.Duration) {
+	var slept []time.Duration
+	s := newSupervisor(c, zap.NewNop())
+	// no jitter, the backoff is half of its maximum.
+	s.jitter = func(int64) int64 { return 0 }
+	s.sleep = func(_ context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	return s, &slept
+}
+
+func TestSupervisor_Backoff(t *testing.T) {
+	s, slept := newTestSupervisor(&config.Retry{MaxRetries: 5, InitialBackoff: time.Second, MaxBackoff: 5 * time.Second})
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, s.retry(context.Background(), "consume", sarama.ErrOutOfBrokers))
+	}
+	assert.Equal(t, []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second, 2500 * time.Millisecond, 2500 * time.Millisecond}, *slept)
+	// the budget is exhausted
+	assert.ErrorContains(t, s.retry(context.Background(), "consume", sarama.ErrOutOfBrokers), "failed to consume after 5 retries")
+
+	// a success resets the budget and the backoff
+	s.succeeded()
+	assert.NoError(t, s.retry(context.Background(), "consume", sarama.ErrOutOfBrokers))
+	assert.Equal(t, 500*time.Millisecond, (*slept)[5])
+}
+
+func TestSupervisor_Jitter(t *testing.T) {
+	s := newSupervisor(&config.Retry{InitialBackoff: time.Second}, zap.NewNop())
+	for i := 0; i < 100; i++ {
+		d := s.backoff()
+		assert.GreaterOrEqual(t, d, 500*time.Millisecond)
+		assert.LessOrEqual(t, d, time.Second)
+	}
+}
+
+func TestSupervisor_Fatal(t *testing.T) {
+	s, slept := newTestSupervisor(nil)
+	assert.ErrorIs(t, s.retry(context.Background(), "connect", sarama.ErrSASLAuthenticationFailed), sarama.ErrSASLAuthenticationFailed)
+	assert.Empty(t, *slept)
+}
+
+func TestSupervisor_RetryForever(t *testing.T) {
+	s, slept := newTestSupervisor(&config.Retry{MaxRetries: -1, MaxBackoff: time.Second})
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, s.retry(context.Background(), "consume", sarama.ErrOutOfBrokers))
+	}
+	assert.Len(t, *slept, 100)
+	assert.Equal(t, 500*time.Millisecond, (*slept)[99])
+}
+
+func TestSupervisor_Cancelled(t *testing.T) {
+	s := newSupervisor(&config.Retry{InitialBackoff: time.Hour}, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, s.retry(ctx, "consume", sarama.ErrOutOfBrokers), context.Canceled)
+}
+
+func TestSupervisor_FailedAfter(t *testing.T) {
+	s, slept := newTestSupervisor(&config.Retry{MaxRetries: 2, InitialBackoff: time.Second})
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, s.retry(context.Background(), "consume", sarama.ErrOutOfBrokers))
+	}
+	// a failure right after the retries doesn't reset them
+	s.failedAfter(time.Second)
+	assert.Error(t, s.retry(context.Background(), "consume", sarama.ErrOutOfBrokers))
+
+	// a failure after a healthy session does
+	s.failedAfter(defaultHealthyPeriod)
+	assert.NoError(t, s.retry(context.Background(), "consume", sarama.ErrOutOfBrokers))
+	assert.Equal(t, 500*time.Millisecond, (*slept)[2])
+}
+
+func TestStart_GivesUp(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	saramaConfig := sarama.NewConfig()
+	// an invalid config can't be fixed by retrying
+	saramaConfig.ClientID = ""
+	k := &kafkaSource{
+		brokers:      []string{"127.0.0.1:1"},
+		config:       saramaConfig,
+		handler:      newConsumerHandler(1),
+		lifecycleCtx: ctx,
+		cancelFn:     cancel,
+		stopCh:       make(chan struct{}),
+		errCh:        make(chan error, 1),
+		logger:       zap.NewNop(),
+	}
+	k.Start()
+	select {
+	case err := <-k.Err():
+		assert.ErrorContains(t, err, "giving up connecting to kafka")
+	default:
+		t.Fatal("the source didn't report giving up")
+	}
+	assert.NoError(t, k.Close())
+}
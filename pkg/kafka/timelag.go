@@ -0,0 +1,128 @@
+package kafka
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// timeLagConcurrency bounds the number of partitions whose records are fetched at the same time.
+const timeLagConcurrency = 8
+
+// newestRecordLookback bounds how far before the newest offset the newest record is looked for. The last offsets of a
+// partition can be markers of transactions, which hold no record.
+const newestRecordLookback = 64
+
+// computeTimeLag computes the time lag of every partition of the report. In time lag pending mode, the total of the
+// report is replaced by the time lag converted to an estimated number of messages, using the previous report to
+// compute the rate at which every partition is produced to.
+func (k *kafkaSource) computeTimeLag(r *lagReport, previous *lagReport) {
+	errs := k.fetchTimeLags(r)
+	var maxTimeLag time.Duration
+	estimated := int64(0)
+	for partition, p := range r.partitions {
+		if err, ok := errs[partition]; ok {
+			k.logger.Warn("Failed to compute the time lag", zap.Int32("partition", partition), zap.Error(err))
+			estimated += p.lag
+			continue
+		}
+		if p.timeLag > maxTimeLag {
+			maxTimeLag = p.timeLag
+		}
+		produced, elapsed := int64(0), time.Duration(0)
+		if previous != nil {
+			if prev, ok := previous.partitions[partition]; ok {
+				produced, elapsed = p.newest-prev.newest, r.computedAt.Sub(previous.computedAt)
+			}
+		}
+		estimated += estimatePending(p.timeLag, p.lag, produced, elapsed)
+		k.logger.Debug("Partition time lag", zap.Int32("partition", partition), zap.Duration("timeLag", p.timeLag), zap.Int64("lag", p.lag))
+	}
+	k.logger.Info("Time lag", zap.String("topic", k.topic), zap.Duration("maxTimeLag", maxTimeLag), zap.Int64("estimatedPending", estimated))
+	if k.timeLag.Pending {
+		r.total = estimated
+	}
+}
+
+// fetchTimeLags computes the time lag of the lagging partitions of the report, fetching their records concurrently.
+// It returns the errors by partition.
+func (k *kafkaSource) fetchTimeLags(r *lagReport) map[int32]error {
+	errs := make(map[int32]error)
+	lock := new(sync.Mutex)
+	sem := make(chan struct{}, timeLagConcurrency)
+	wg := new(sync.WaitGroup)
+	for partition, p := range r.partitions {
+		if p.lag <= 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(partition int32, p *partitionReport) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			timeLag, err := k.partitionTimeLag(partition, p.start, p.newest)
+			if err != nil {
+				lock.Lock()
+				errs[partition] = err
+				lock.Unlock()
+				return
+			}
+			p.timeLag = timeLag
+		}(partition, p)
+	}
+	wg.Wait()
+	return errs
+}
+
+// partitionTimeLag returns how far the timestamp of the record at the start offset trails the newest record. The record
+// at the start offset may have been compacted away, the next one is used then. The newest record is looked for before
+// the newest offset, past the markers of transactions.
+func (k *kafkaSource) partitionTimeLag(partition int32, start, newest int64) (time.Duration, error) {
+	records, _, err := fetchRecords(k.saramaClient, k.config, k.topic, partition, start)
+	if err != nil {
+		return 0, err
+	}
+	if len(records) == 0 || records[0].offset >= newest {
+		// the offsets up to the newest one hold no record.
+		return 0, nil
+	}
+	startTime := records[0].timestamp
+	for back := int64(1); ; back *= 2 {
+		from := newest - back
+		if from <= start {
+			from = start
+		}
+		if records, _, err = fetchRecords(k.saramaClient, k.config, k.topic, partition, from); err != nil {
+			return 0, err
+		}
+		var newestTime time.Time
+		found := false
+		for _, record := range records {
+			if record.offset < newest {
+				newestTime, found = record.timestamp, true
+			}
+		}
+		switch {
+		case found && newestTime.Before(startTime):
+			return 0, nil
+		case found:
+			return newestTime.Sub(startTime), nil
+		case from == start || back >= newestRecordLookback:
+			return 0, fmt.Errorf("no record found in the last %d offsets of partition %d before offset %d", newest-from, partition, newest)
+		}
+	}
+}
+
+// estimatePending converts the time lag of a partition to a number of messages, assuming the partition keeps being
+// produced to at the rate observed between two refreshes. It falls back to the message count lag when the rate is
+// unknown.
+func estimatePending(timeLag time.Duration, lag, produced int64, elapsed time.Duration) int64 {
+	if elapsed <= 0 || produced <= 0 {
+		return lag
+	}
+	rate := float64(produced) / elapsed.Seconds()
+	return int64(math.Ceil(rate * timeLag.Seconds()))
+}
@@ -0,0 +1,65 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+func TestEstimatePending(t *testing.T) {
+	// 100 messages produced in 10s, 30s behind
+	assert.Equal(t, int64(300), estimatePending(30*time.Second, 50, 100, 10*time.Second))
+	// the rate is unknown without a previous refresh or when nothing was produced
+	assert.Equal(t, int64(50), estimatePending(30*time.Second, 50, 0, 0))
+	assert.Equal(t, int64(50), estimatePending(30*time.Second, 50, 0, 10*time.Second))
+	assert.Equal(t, int64(0), estimatePending(0, 0, 100, 10*time.Second))
+}
+
+func TestComputeTimeLag(t *testing.T) {
+	produced := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	fetch := &sarama.FetchResponse{Version: 4}
+	for o, delay := range []time.Duration{0, 10 * time.Second, 30 * time.Second} {
+		fetch.AddRecordWithTimestamp("test-topic", 0, nil, sarama.StringEncoder("v"), int64(o), produced.Add(delay))
+	}
+	// the newest offset of partition 0 is the marker of the transaction the records were produced in
+	fetch.AddControlRecord("test-topic", 0, 3, 1, sarama.ControlRecordCommit)
+	fetch.AddError("test-topic", 1, sarama.ErrNotLeaderForPartition)
+
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("test-topic", 0, broker.BrokerID()).
+			SetLeader("test-topic", 1, broker.BrokerID()),
+		"FetchRequest": sarama.NewMockWrapper(fetch),
+	})
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.Version = sarama.V2_1_0_0
+	client, err := sarama.NewClient([]string{broker.Addr()}, saramaConfig)
+	require.NoError(t, err)
+	defer client.Close()
+
+	k := &kafkaSource{
+		topic:        "test-topic",
+		config:       saramaConfig,
+		saramaClient: client,
+		timeLag:      &config.TimeLag{Pending: true},
+		logger:       zap.NewNop(),
+	}
+	r := &lagReport{partitions: map[int32]*partitionReport{
+		0: {start: 0, newest: 4, lag: 4},
+		1: {start: 10, newest: 15, lag: 5},
+	}}
+	k.computeTimeLag(r, nil)
+	assert.Equal(t, 30*time.Second, r.partitions[0].timeLag)
+	// the lag of the partition that failed is reported as is
+	assert.Equal(t, time.Duration(0), r.partitions[1].timeLag)
+	assert.Equal(t, int64(9), r.total)
+}
@@ -0,0 +1,94 @@
+package kafka
+
+import (
+	"context"
+	"time"
+
+	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/tracing"
+)
+
+const consumeSpanName = "kafka.consume"
+
+// recordTracer starts the consume spans of the records read from the topic.
+type recordTracer struct {
+	tracer        trace.Tracer
+	propagator    propagation.TextMapPropagator
+	topic         string
+	consumerGroup string
+	// one span per read, linked to the records, instead of one per record.
+	batch bool
+}
+
+func newRecordTracer(tp trace.TracerProvider, topic, consumerGroup string, batch bool) *recordTracer {
+	return &recordTracer{
+		tracer:        tp.Tracer(tracing.TracerName),
+		propagator:    propagation.TraceContext{},
+		topic:         topic,
+		consumerGroup: consumerGroup,
+		batch:         batch,
+	}
+}
+
+// startRecordSpan starts the consume span of a record, as a child of the trace context in its headers. The context of
+// the span replaces the one in the headers, so that it is carried downstream.
+func (t *recordTracer) startRecordSpan(m *sarama.ConsumerMessage) trace.Span {
+	carrier := tracing.NewHeadersCarrier(m)
+	ctx := t.propagator.Extract(context.Background(), carrier)
+	ctx, span := t.tracer.Start(ctx, consumeSpanName,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(t.attributes()...),
+		trace.WithAttributes(
+			semconv.MessagingKafkaDestinationPartition(int(m.Partition)),
+			semconv.MessagingKafkaMessageOffset(int(m.Offset)),
+			semconv.MessagingMessagePayloadSizeBytes(len(m.Value)),
+		),
+	)
+	t.propagator.Inject(ctx, carrier)
+	return span
+}
+
+// recordLink returns the link to the trace context in the headers of a record, and false if there is none.
+func (t *recordTracer) recordLink(m *sarama.ConsumerMessage) (trace.Link, bool) {
+	sc := trace.SpanContextFromContext(t.propagator.Extract(context.Background(), tracing.NewHeadersCarrier(m)))
+	if !sc.IsValid() {
+		return trace.Link{}, false
+	}
+	return trace.Link{
+		SpanContext: sc,
+		Attributes: []attribute.KeyValue{
+			semconv.MessagingKafkaDestinationPartition(int(m.Partition)),
+			semconv.MessagingKafkaMessageOffset(int(m.Offset)),
+		},
+	}, true
+}
+
+// recordBatchSpan records the consume span of a read that started at start, linked to the trace context of the records.
+// The record headers are left as they are, since the span is only known once the read is over.
+func (t *recordTracer) recordBatchSpan(start time.Time, count int, links []trace.Link) {
+	if count == 0 {
+		return
+	}
+	_, span := t.tracer.Start(context.Background(), consumeSpanName,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithTimestamp(start),
+		trace.WithLinks(links...),
+		trace.WithAttributes(t.attributes()...),
+		trace.WithAttributes(semconv.MessagingBatchMessageCount(count)),
+	)
+	span.End()
+}
+
+func (t *recordTracer) attributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.MessagingSystem("kafka"),
+		semconv.MessagingOperationReceive,
+		semconv.MessagingDestinationName(t.topic),
+		semconv.MessagingKafkaConsumerGroup(t.consumerGroup),
+	}
+}
@@ -0,0 +1,118 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+const (
+	producerTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	producerSpanID      = "00f067aa0ba902b7"
+	producerTraceparent = "00-" + producerTraceID + "-" + producerSpanID + "-01"
+)
+
+func TestRecordTracer_RecordSpan(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tr := newRecordTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)), "test-topic", "test-group", false)
+	m := &sarama.ConsumerMessage{
+		Topic:     "test-topic",
+		Partition: 1,
+		Offset:    42,
+		Headers:   headers("traceparent", producerTraceparent),
+	}
+	tr.startRecordSpan(m).End()
+
+	spans := sr.Ended()
+	assert.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, consumeSpanName, span.Name())
+	assert.Equal(t, trace.SpanKindConsumer, span.SpanKind())
+	assert.Equal(t, producerTraceID, span.Parent().TraceID().String())
+	assert.Equal(t, producerSpanID, span.Parent().SpanID().String())
+	assert.Contains(t, span.Attributes(), semconv.MessagingKafkaMessageOffset(42))
+	assert.Contains(t, span.Attributes(), semconv.MessagingKafkaDestinationPartition(1))
+
+	// the headers carry the consume span downstream
+	traceparent, ok := header(m, "traceparent")
+	assert.True(t, ok)
+	assert.Equal(t, "00-"+producerTraceID+"-"+span.SpanContext().SpanID().String()+"-01", string(traceparent))
+	assert.Len(t, m.Headers, 1)
+}
+
+func TestRecordTracer_RecordSpanWithoutContext(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tr := newRecordTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)), "test-topic", "test-group", false)
+	m := &sarama.ConsumerMessage{Topic: "test-topic"}
+	tr.startRecordSpan(m).End()
+
+	spans := sr.Ended()
+	assert.Len(t, spans, 1)
+	assert.False(t, spans[0].Parent().IsValid())
+	// a new trace is started and added to the headers
+	_, ok := header(m, "traceparent")
+	assert.True(t, ok)
+}
+
+func TestRecordTracer_BatchSpan(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tr := newRecordTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)), "test-topic", "test-group", true)
+	traced := &sarama.ConsumerMessage{Partition: 0, Offset: 1, Headers: headers("traceparent", producerTraceparent)}
+	untraced := &sarama.ConsumerMessage{Partition: 0, Offset: 2}
+
+	var links []trace.Link
+	for _, m := range []*sarama.ConsumerMessage{traced, untraced} {
+		if link, ok := tr.recordLink(m); ok {
+			links = append(links, link)
+		}
+	}
+	start := time.Now().Add(-time.Second)
+	tr.recordBatchSpan(start, 2, links)
+	// nothing is recorded for an empty read
+	tr.recordBatchSpan(start, 0, nil)
+
+	spans := sr.Ended()
+	assert.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, start, span.StartTime())
+	assert.Contains(t, span.Attributes(), semconv.MessagingBatchMessageCount(2))
+	assert.Len(t, span.Links(), 1)
+	assert.Equal(t, producerSpanID, span.Links()[0].SpanContext.SpanID().String())
+	// the record headers are left as they are
+	traceparent, _ := header(traced, "traceparent")
+	assert.Equal(t, producerTraceparent, string(traceparent))
+	assert.Empty(t, untraced.Headers)
+}
+
+func TestCloudEventsDecoder_TraceContext(t *testing.T) {
+	d := newCloudEventsDecoder(&config.CloudEvents{})
+	ce, err := d.decode(&sarama.ConsumerMessage{
+		Headers: headers(
+			"ce_specversion", "1.0",
+			"ce_id", "1",
+			"ce_source", "/orders",
+			"ce_type", "order.created",
+			"traceparent", producerTraceparent,
+		),
+	})
+	assert.NoError(t, err)
+	traceparent, _ := ce.attribute("traceparent")
+	assert.Equal(t, producerTraceparent, traceparent)
+
+	// the trace context of the event is kept
+	ce, err = d.decode(&sarama.ConsumerMessage{
+		Headers: headers("content-type", "application/cloudevents+json", "traceparent", producerTraceparent),
+		Value:   []byte(`{"specversion":"1.0","id":"1","source":"/orders","type":"order.created","traceparent":"event"}`),
+	})
+	assert.NoError(t, err)
+	traceparent, _ = ce.attribute("traceparent")
+	assert.Equal(t, "event", traceparent)
+}
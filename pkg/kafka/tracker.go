@@ -0,0 +1,166 @@
+package kafka
+
+import (
+	"container/list"
+	"sync"
+)
+
+// offsetTracker keeps the offsets read from every partition in read order, so that an offset is only committed once it
+// and every offset read before it on the same partition have been acked.
+type offsetTracker struct {
+	lock       sync.Mutex
+	partitions map[topicPartition]*partitionOffsets
+}
+
+// topicPartition identifies a partition. The topic is part of it, as a reload can switch topics while the acks of the
+// previous topic are still in flight.
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+// partitionOffsets holds the not yet committed offsets of one partition.
+type partitionOffsets struct {
+	// offsets in read order
+	order *list.List
+	index map[int64]*list.Element
+	// offsets that are acked together with the key offset
+	members map[int64][]int64
+}
+
+type trackedOffset struct {
+	offset int64
+	acked  bool
+	// sub indices of the messages the record was split into that are not acked yet
+	children map[int32]struct{}
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{
+		partitions: make(map[topicPartition]*partitionOffsets),
+	}
+}
+
+func (t *offsetTracker) partition(topic string, partition int32) *partitionOffsets {
+	tp := topicPartition{topic: topic, partition: partition}
+	p, ok := t.partitions[tp]
+	if !ok {
+		p = &partitionOffsets{
+			order:   list.New(),
+			index:   make(map[int64]*list.Element),
+			members: make(map[int64][]int64),
+		}
+		t.partitions[tp] = p
+	}
+	return p
+}
+
+// track records an offset read from a partition.
+func (t *offsetTracker) track(topic string, partition int32, offset int64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	p := t.partition(topic, partition)
+	if _, ok := p.index[offset]; ok {
+		return
+	}
+	p.index[offset] = p.order.PushBack(&trackedOffset{offset: offset})
+}
+
+// bind makes acking offset also ack the members, e.g. the chunks a reassembled message was built from.
+func (t *offsetTracker) bind(topic string, partition int32, offset int64, members []int64) {
+	if len(members) == 0 {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	p := t.partition(topic, partition)
+	p.members[offset] = append(p.members[offset], members...)
+}
+
+// expand makes an offset wait for the acks of the n messages its record was split into.
+func (t *offsetTracker) expand(topic string, partition int32, offset int64, n int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	e, ok := t.partition(topic, partition).index[offset]
+	if !ok {
+		return
+	}
+	to := e.Value.(*trackedOffset)
+	to.children = make(map[int32]struct{}, n)
+	for i := 0; i < n; i++ {
+		to.children[int32(i)] = struct{}{}
+	}
+}
+
+// ack acks an offset and its bound members. It returns the highest offset of the partition that is safe to commit,
+// and false if the ack didn't move it.
+func (t *offsetTracker) ack(topic string, partition int32, offset int64) (int64, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	p, ok := t.partitions[topicPartition{topic: topic, partition: partition}]
+	if !ok {
+		return 0, false
+	}
+	p.ack(offset)
+	return p.advance()
+}
+
+// ackChild acks one of the messages a record was split into, the offset of the record is acked with its last message.
+// It returns the highest offset of the partition that is safe to commit, and false if the ack didn't move it.
+func (t *offsetTracker) ackChild(topic string, partition int32, offset int64, subIndex int32) (int64, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	p, ok := t.partitions[topicPartition{topic: topic, partition: partition}]
+	if !ok {
+		return 0, false
+	}
+	e, ok := p.index[offset]
+	if !ok {
+		return 0, false
+	}
+	to := e.Value.(*trackedOffset)
+	delete(to.children, subIndex)
+	if len(to.children) == 0 {
+		p.ack(offset)
+	}
+	return p.advance()
+}
+
+// ack marks an offset and its bound members, as well as their own members, as acked.
+func (p *partitionOffsets) ack(offset int64) {
+	if e, ok := p.index[offset]; ok {
+		e.Value.(*trackedOffset).acked = true
+	}
+	members := p.members[offset]
+	delete(p.members, offset)
+	for _, o := range members {
+		p.ack(o)
+	}
+}
+
+// advance drops the leading acked offsets and returns the last one dropped.
+func (p *partitionOffsets) advance() (int64, bool) {
+	committable, moved := int64(0), false
+	for e := p.order.Front(); e != nil && e.Value.(*trackedOffset).acked; e = p.order.Front() {
+		committable, moved = e.Value.(*trackedOffset).offset, true
+		delete(p.index, committable)
+		p.order.Remove(e)
+	}
+	return committable, moved
+}
+
+// retain drops the offsets of the partitions that are no longer assigned, after a rebalance, and those of the other
+// topics. They will be read again by the consumer the partitions are assigned to.
+func (t *offsetTracker) retain(topic string, partitions []int32) {
+	assigned := make(map[topicPartition]struct{}, len(partitions))
+	for _, p := range partitions {
+		assigned[topicPartition{topic: topic, partition: p}] = struct{}{}
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for tp := range t.partitions {
+		if _, ok := assigned[tp]; !ok {
+			delete(t.partitions, tp)
+		}
+	}
+}
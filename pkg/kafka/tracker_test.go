@@ -0,0 +1,84 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOffsetTracker_CommitsInReadOrder(t *testing.T) {
+	tracker := newOffsetTracker()
+	for _, o := range []int64{10, 11, 13} {
+		tracker.track("test-topic", 0, o)
+	}
+	// 10 isn't acked yet, nothing is safe to commit
+	_, ok := tracker.ack("test-topic", 0, 11)
+	assert.False(t, ok)
+	committable, ok := tracker.ack("test-topic", 0, 10)
+	assert.True(t, ok)
+	assert.Equal(t, int64(11), committable)
+	committable, ok = tracker.ack("test-topic", 0, 13)
+	assert.True(t, ok)
+	assert.Equal(t, int64(13), committable)
+	// unknown offsets and partitions are ignored
+	_, ok = tracker.ack("test-topic", 0, 20)
+	assert.False(t, ok)
+	_, ok = tracker.ack("test-topic", 1, 10)
+	assert.False(t, ok)
+}
+
+func TestOffsetTracker_Bind(t *testing.T) {
+	tracker := newOffsetTracker()
+	for _, o := range []int64{1, 2, 3, 4} {
+		tracker.track("test-topic", 0, o)
+	}
+	tracker.bind("test-topic", 0, 4, []int64{1, 3})
+	_, ok := tracker.ack("test-topic", 0, 2)
+	assert.False(t, ok)
+	committable, ok := tracker.ack("test-topic", 0, 4)
+	assert.True(t, ok)
+	assert.Equal(t, int64(4), committable)
+}
+
+func TestOffsetTracker_Retain(t *testing.T) {
+	tracker := newOffsetTracker()
+	tracker.track("test-topic", 0, 1)
+	tracker.track("test-topic", 1, 1)
+	tracker.retain("test-topic", []int32{1})
+	_, ok := tracker.ack("test-topic", 0, 1)
+	assert.False(t, ok)
+	committable, ok := tracker.ack("test-topic", 1, 1)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), committable)
+}
+
+func TestOffsetTracker_Expand(t *testing.T) {
+	tracker := newOffsetTracker()
+	tracker.track("test-topic", 0, 1)
+	tracker.track("test-topic", 0, 2)
+	tracker.bind("test-topic", 0, 2, []int64{1})
+	tracker.expand("test-topic", 0, 2, 3)
+	_, ok := tracker.ackChild("test-topic", 0, 2, 0)
+	assert.False(t, ok)
+	_, ok = tracker.ackChild("test-topic", 0, 2, 2)
+	assert.False(t, ok)
+	// acking the same child twice doesn't complete the record
+	_, ok = tracker.ackChild("test-topic", 0, 2, 2)
+	assert.False(t, ok)
+	committable, ok := tracker.ackChild("test-topic", 0, 2, 1)
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), committable)
+}
+
+func TestOffsetTracker_BindNested(t *testing.T) {
+	tracker := newOffsetTracker()
+	for _, o := range []int64{1, 2, 3} {
+		tracker.track("test-topic", 0, o)
+	}
+	// 2 is a reassembled message made of 1 and 2, aggregated with 3
+	tracker.bind("test-topic", 0, 2, []int64{1})
+	tracker.bind("test-topic", 0, 3, []int64{2})
+	committable, ok := tracker.ack("test-topic", 0, 3)
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), committable)
+}
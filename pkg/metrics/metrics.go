@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	namespace = "kafka_source"
+
+	LabelTopic     = "topic"
+	LabelPartition = "partition"
+	LabelStage     = "stage"
+	LabelLocation  = "location"
+
+	// LocationBuffered is the location of the pending messages consumed from kafka and waiting in the handler buffer, or
+	// in the backlog of the split and aggregated messages
+	LocationBuffered = "buffered"
+	// LocationBroker is the location of the pending messages still on the broker, or read but not committed yet
+	LocationBroker = "broker"
+)
+
+var (
+	// ReadTotal is the number of records read from kafka
+	ReadTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "read_total",
+		Help:      "Total number of records read from kafka",
+	}, []string{LabelTopic})
+
+	// ReadBytesTotal is the size of the record values read from kafka
+	ReadBytesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "read_bytes_total",
+		Help:      "Total size of the record values read from kafka",
+	}, []string{LabelTopic})
+
+	// AckTotal is the number of messages acked
+	AckTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ack_total",
+		Help:      "Total number of messages acked",
+	}, []string{LabelTopic})
+
+	// DecodeErrorsTotal is the number of records that failed to be decoded by a stage and were emitted as is or dropped
+	DecodeErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "decode_errors_total",
+		Help:      "Total number of records that failed to be decoded",
+	}, []string{LabelTopic, LabelStage})
+
+	// ConsumerErrorsTotal is the number of errors returned by the kafka consumer
+	ConsumerErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "consumer_errors_total",
+		Help:      "Total number of errors returned by the kafka consumer",
+	}, []string{LabelTopic})
+
+	// RebalanceTotal is the number of consumer group sessions started, one per rebalance
+	RebalanceTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rebalance_total",
+		Help:      "Total number of consumer group rebalances",
+	}, []string{LabelTopic})
+
+	// BufferDepth is the number of records consumed from kafka waiting in the handler buffer to be read
+	BufferDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "buffer_depth",
+		Help:      "Number of records waiting in the handler buffer to be read",
+	}, []string{LabelTopic})
+
+	// CommittedOffset is the committed offset of the consumer group for a partition
+	CommittedOffset = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "committed_offset",
+		Help:      "Committed offset of the consumer group, per partition",
+	}, []string{LabelTopic, LabelPartition})
+
+	// PartitionLag is the number of messages of a partition still to be processed by the consumer group
+	PartitionLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "partition_lag",
+		Help:      "Number of messages still to be processed by the consumer group, per partition",
+	}, []string{LabelTopic, LabelPartition})
+
+	// PartitionTimeLag is how far the record at the committed offset of a partition trails the newest record
+	PartitionTimeLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "partition_time_lag_seconds",
+		Help:      "How far the record at the committed offset trails the newest record, per partition",
+	}, []string{LabelTopic, LabelPartition})
+
+	// PendingMessages is the number of pending messages last reported, by where they are
+	PendingMessages = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pending_messages",
+		Help:      "Number of pending messages last reported, by location",
+	}, []string{LabelTopic, LabelLocation})
+
+	// PendingAge is the age of the pending messages last reported, or of the last computed ones once they are too old
+	// to be reported
+	PendingAge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pending_age_seconds",
+		Help:      "Age of the last computed pending messages",
+	}, []string{LabelTopic})
+
+	// PendingDuration is the latency of the pending messages requests
+	PendingDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "pending_duration_seconds",
+		Help:      "Latency of the pending messages requests",
+	}, []string{LabelTopic})
+
+	// LagRefreshDuration is the time it takes to compute the pending messages in the background
+	LagRefreshDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "lag_refresh_duration_seconds",
+		Help:      "Time it takes to compute the pending messages in the background",
+	}, []string{LabelTopic})
+)
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/utils"
+)
+
+// DefaultAddr is the address the metrics server listens on by default.
+const DefaultAddr = ":9090"
+
+// Server serves the metrics endpoint over HTTP.
+type Server struct {
+	mux    *http.ServeMux
+	srv    *http.Server
+	logger *zap.SugaredLogger
+}
+
+// NewServer creates a server listening on addr, which serves the metrics on /metrics.
+func NewServer(addr string) *Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return &Server{
+		mux: mux,
+		srv: &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+		logger: utils.NewLogger(),
+	}
+}
+
+// Handler returns the handler serving all the endpoints of the server.
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// Handle registers an additional endpoint on the server, e.g. the health probes.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Start starts serving in the background.
+func (s *Server) Start() {
+	go func() {
+		s.logger.Infow("Starting metrics server", "addr", s.srv.Addr)
+		if err := s.srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Errorw("Metrics server failed", zap.Error(err))
+		}
+	}()
+}
+
+// Shutdown stops the server gracefully.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(t *testing.T, s *Server) string {
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	resp, err := http.Get(ts.URL + "/metrics")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return string(body)
+}
+
+func TestServer_Metrics(t *testing.T) {
+	s := NewServer(DefaultAddr)
+	ReadTotal.WithLabelValues("test-topic").Add(3)
+	ReadBytesTotal.WithLabelValues("test-topic").Add(42)
+	AckTotal.WithLabelValues("test-topic").Inc()
+	DecodeErrorsTotal.WithLabelValues("test-topic", "split").Inc()
+	ConsumerErrorsTotal.WithLabelValues("test-topic").Inc()
+	RebalanceTotal.WithLabelValues("test-topic").Inc()
+	BufferDepth.WithLabelValues("test-topic").Set(7)
+	CommittedOffset.WithLabelValues("test-topic", "0").Set(100)
+	PartitionLag.WithLabelValues("test-topic", "0").Set(5)
+	PendingDuration.WithLabelValues("test-topic").Observe(0.01)
+
+	body := scrape(t, s)
+	assert.Contains(t, body, `kafka_source_read_total{topic="test-topic"} 3`)
+	assert.Contains(t, body, `kafka_source_read_bytes_total{topic="test-topic"} 42`)
+	assert.Contains(t, body, `kafka_source_ack_total{topic="test-topic"} 1`)
+	assert.Contains(t, body, `kafka_source_decode_errors_total{stage="split",topic="test-topic"} 1`)
+	assert.Contains(t, body, `kafka_source_consumer_errors_total{topic="test-topic"} 1`)
+	assert.Contains(t, body, `kafka_source_rebalance_total{topic="test-topic"} 1`)
+	assert.Contains(t, body, `kafka_source_buffer_depth{topic="test-topic"} 7`)
+	assert.Contains(t, body, `kafka_source_committed_offset{partition="0",topic="test-topic"} 100`)
+	assert.Contains(t, body, `kafka_source_partition_lag{partition="0",topic="test-topic"} 5`)
+	assert.Contains(t, body, `kafka_source_pending_duration_seconds_count{topic="test-topic"} 1`)
+}
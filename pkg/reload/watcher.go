@@ -0,0 +1,172 @@
+// Package reload watches the mounted config file and the secrets it refers to, and reloads the source when they change.
+package reload
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+	"github.com/numaproj-contrib/kafka-source-go/pkg/utils"
+)
+
+// DefaultDebounce is how long the watcher waits for the events to settle before reloading. Kubernetes updates a
+// mounted volume with several renames and removals.
+const DefaultDebounce = time.Second
+
+// Reloader applies a config loaded again while the source runs.
+type Reloader interface {
+	// Reload applies the config, secretsChanged tells that the content of the mounted secrets changed.
+	Reload(c *config.Config, secretsChanged bool) error
+}
+
+// Watcher watches the directories of the config file and of the secrets the config refers to. The directories are
+// watched rather than the files, as Kubernetes updates the mounted volumes by swapping a symlink.
+type Watcher struct {
+	path     string
+	load     func() (*config.Config, error)
+	reader   utils.VolumeReader
+	target   Reloader
+	debounce time.Duration
+	logger   *zap.Logger
+
+	watcher *fsnotify.Watcher
+	// watched directories
+	dirs map[string]bool
+	// last config loaded, and hash of the content of its secrets
+	config  *config.Config
+	secrets [sha256.Size]byte
+}
+
+// NewWatcher watches the config file at path, which the running config c was loaded from with load. The secrets are
+// read with reader.
+func NewWatcher(path string, c *config.Config, load func() (*config.Config, error), reader utils.VolumeReader, target Reloader, logger *zap.Logger) (*Watcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher, %w", err)
+	}
+	w := &Watcher{
+		path:     path,
+		load:     load,
+		reader:   reader,
+		target:   target,
+		debounce: DefaultDebounce,
+		logger:   logger,
+		watcher:  fw,
+		dirs:     make(map[string]bool),
+		config:   c,
+		secrets:  hashSecrets(c, reader),
+	}
+	if err := w.watch(c); err != nil {
+		_ = fw.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// watch adds the directories of the config file and of the secrets of c to the watched ones.
+func (w *Watcher) watch(c *config.Config) error {
+	dirs := []string{filepath.Dir(w.path)}
+	for _, s := range c.Secrets() {
+		if path, err := w.reader.GetSecretVolumePath(s); err == nil {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+	}
+	for _, dir := range dirs {
+		if w.dirs[dir] {
+			continue
+		}
+		if err := w.watcher.Add(dir); err != nil {
+			if dir == filepath.Dir(w.path) {
+				return fmt.Errorf("failed to watch %s, %w", dir, err)
+			}
+			// the secret may be mounted later, its change is picked up with the next change of the config.
+			w.logger.Warn("Failed to watch a secret directory", zap.String("dir", dir), zap.Error(err))
+			continue
+		}
+		w.dirs[dir] = true
+	}
+	return nil
+}
+
+// Run reloads the source whenever the watched files change, until ctx is done.
+func (w *Watcher) Run(ctx context.Context) {
+	defer func() {
+		_ = w.watcher.Close()
+	}()
+	w.logger.Info("Watching the config for changes", zap.String("path", w.path))
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			timer.Reset(w.debounce)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.logger.Warn("Config watcher error", zap.Error(err))
+		case <-timer.C:
+			w.check()
+		}
+	}
+}
+
+// check loads the config again, and reloads the source if the config or the content of its secrets changed.
+func (w *Watcher) check() {
+	c, err := w.load()
+	if err == nil {
+		err = c.Validate()
+	}
+	if err != nil {
+		w.logger.Error("Not reloading an invalid config", zap.Error(err))
+		return
+	}
+	secrets := hashSecrets(c, w.reader)
+	configChanged := !reflect.DeepEqual(c, w.config)
+	secretsChanged := secrets != w.secrets
+	if !configChanged && !secretsChanged {
+		return
+	}
+	if err := w.watch(c); err != nil {
+		w.logger.Warn("Failed to watch the config", zap.Error(err))
+	}
+	if err := w.target.Reload(c, secretsChanged); err != nil {
+		// the change is compared against the running config again on the next change.
+		w.logger.Error("Config change not applied", zap.Error(err))
+		return
+	}
+	w.config, w.secrets = c, secrets
+}
+
+// hashSecrets returns the hash of the content of the secrets of a config. The secrets that can't be read hash as empty.
+func hashSecrets(c *config.Config, reader utils.VolumeReader) [sha256.Size]byte {
+	h := sha256.New()
+	for _, s := range c.Secrets() {
+		_, _ = fmt.Fprintf(h, "%s/%s\x00", s.Name, s.Key)
+		if path, err := reader.GetSecretVolumePath(s); err == nil {
+			content, _ := os.ReadFile(path)
+			_, _ = h.Write(content)
+		}
+		_, _ = h.Write([]byte{0})
+	}
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
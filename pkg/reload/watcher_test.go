@@ -0,0 +1,130 @@
+package reload
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+	"github.com/numaproj-contrib/kafka-source-go/pkg/utils"
+)
+
+type reload struct {
+	config         *config.Config
+	secretsChanged bool
+}
+
+type fakeReloader struct {
+	reloads chan reload
+	err     error
+}
+
+func (f *fakeReloader) Reload(c *config.Config, secretsChanged bool) error {
+	f.reloads <- reload{config: c, secretsChanged: secretsChanged}
+	return f.err
+}
+
+const watchedConfig = `
+brokers: [broker-1:9092]
+topic: test-topic
+consumerGroup: test-group
+tls:
+  caCertSecret:
+    name: kafka-tls
+    key: ca.crt
+`
+
+func writeFile(t *testing.T, path string, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func newTestWatcher(t *testing.T, target Reloader) (*Watcher, string, string) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config", "kafka-config.yaml")
+	secretsDir := filepath.Join(dir, "secrets")
+	writeFile(t, path, watchedConfig)
+	writeFile(t, filepath.Join(secretsDir, "kafka-tls", "ca.crt"), "ca-1")
+
+	parser := &config.YAMLConfigParser{}
+	load := func() (*config.Config, error) {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return parser.Parse(string(content))
+	}
+	c, err := load()
+	require.NoError(t, err)
+	w, err := NewWatcher(path, c, load, utils.NewKafkaVolumeReader(secretsDir), target, zap.NewNop())
+	require.NoError(t, err)
+	w.debounce = 10 * time.Millisecond
+	return w, path, secretsDir
+}
+
+func awaitReload(t *testing.T, f *fakeReloader) reload {
+	select {
+	case r := <-f.reloads:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("the source was not reloaded")
+		return reload{}
+	}
+}
+
+func TestWatcher_Run(t *testing.T) {
+	f := &fakeReloader{reloads: make(chan reload, 1)}
+	w, path, secretsDir := newTestWatcher(t, f)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	writeFile(t, path, `
+brokers: [broker-1:9092]
+topic: other-topic
+consumerGroup: test-group
+tls:
+  caCertSecret:
+    name: kafka-tls
+    key: ca.crt
+`)
+	r := awaitReload(t, f)
+	assert.Equal(t, "other-topic", r.config.Topic)
+	assert.False(t, r.secretsChanged)
+
+	writeFile(t, filepath.Join(secretsDir, "kafka-tls", "ca.crt"), "ca-2")
+	r = awaitReload(t, f)
+	assert.Equal(t, "other-topic", r.config.Topic)
+	assert.True(t, r.secretsChanged)
+}
+
+func TestWatcher_Check(t *testing.T) {
+	f := &fakeReloader{reloads: make(chan reload, 1)}
+	w, path, _ := newTestWatcher(t, f)
+	running := w.config
+
+	// unchanged
+	w.check()
+	assert.Len(t, f.reloads, 0)
+
+	// an invalid config is not applied
+	writeFile(t, path, "brokers: [broker-1:9092]\ntopic: other-topic\n")
+	w.check()
+	assert.Len(t, f.reloads, 0)
+	assert.Same(t, running, w.config)
+
+	// a refused change is compared against the running config again
+	writeFile(t, path, "brokers: [broker-1:9092]\ntopic: test-topic\nconsumerGroup: other-group\n")
+	f.err = assert.AnError
+	w.check()
+	assert.Equal(t, "other-group", awaitReload(t, f).config.ConsumerGroupName)
+	assert.Same(t, running, w.config)
+	w.check()
+	assert.Len(t, f.reloads, 1)
+}
@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// HeadersCarrier adapts the headers of a kafka record to a propagation.TextMapCarrier.
+type HeadersCarrier struct {
+	headers *[]*sarama.RecordHeader
+}
+
+var _ propagation.TextMapCarrier = (*HeadersCarrier)(nil)
+
+// NewHeadersCarrier creates a carrier reading and writing the headers of a record.
+func NewHeadersCarrier(m *sarama.ConsumerMessage) *HeadersCarrier {
+	return &HeadersCarrier{headers: &m.Headers}
+}
+
+// Get returns the value of the first header with the given key.
+func (c *HeadersCarrier) Get(key string) string {
+	for _, h := range *c.headers {
+		if h != nil && string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Set replaces the value of the header with the given key, or adds it.
+func (c *HeadersCarrier) Set(key string, value string) {
+	for _, h := range *c.headers {
+		if h != nil && string(h.Key) == key {
+			h.Value = []byte(value)
+			return
+		}
+	}
+	*c.headers = append(*c.headers, &sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+// Keys returns the keys of all the headers.
+func (c *HeadersCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.headers))
+	for _, h := range *c.headers {
+		if h != nil {
+			keys = append(keys, string(h.Key))
+		}
+	}
+	return keys
+}
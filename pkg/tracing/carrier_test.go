@@ -0,0 +1,29 @@
+package tracing
+
+import (
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeadersCarrier(t *testing.T) {
+	m := &sarama.ConsumerMessage{
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte("traceparent"), Value: []byte("producer")},
+			nil,
+			{Key: []byte("other"), Value: []byte("value")},
+		},
+	}
+	c := NewHeadersCarrier(m)
+	assert.Equal(t, "producer", c.Get("traceparent"))
+	assert.Equal(t, "", c.Get("tracestate"))
+	assert.Equal(t, []string{"traceparent", "other"}, c.Keys())
+
+	c.Set("traceparent", "consumer")
+	c.Set("tracestate", "state")
+	assert.Equal(t, "consumer", c.Get("traceparent"))
+	assert.Equal(t, "state", c.Get("tracestate"))
+	assert.Len(t, m.Headers, 4)
+	assert.Equal(t, "consumer", string(m.Headers[0].Value))
+}
@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+
+	"github.com/numaproj-contrib/kafka-source-go/pkg/config"
+)
+
+// TracerName is the name of the tracer the consume spans are started with.
+const TracerName = "github.com/numaproj-contrib/kafka-source-go"
+
+const defaultServiceName = "kafka-source"
+
+// NewTracerProvider creates a tracer provider exporting the spans as configured.
+func NewTracerProvider(ctx context.Context, c *config.Tracing) (*sdktrace.TracerProvider, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch c.Exporter {
+	case config.TracingExporterOTLP, "":
+		var opts []otlptracegrpc.Option
+		if c.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(c.Endpoint))
+		}
+		if c.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, opts...)
+	case config.TracingExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("failed to parse tracing exporter %q. Must be one of the following: ['%s', '%s']", c.Exporter, config.TracingExporterOTLP, config.TracingExporterStdout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s span exporter, %w", c.Exporter, err)
+	}
+	serviceName := c.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	), nil
+}